@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+// unifiedDiff renders the line differences between want and got as a unified diff,
+// it returns an empty string when both are equal.
+func unifiedDiff(wantName string, want string, gotName string, got string) string {
+	if want == got {
+		return ""
+	}
+	a := splitLines(want)
+	b := splitLines(got)
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", wantName, gotName)
+	for start := 0; start < len(ops); {
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		hunkStart := max(start-diffContext, 0)
+		hunkEnd := start
+		for idx := start; idx < len(ops); idx++ {
+			if ops[idx].kind != ' ' {
+				hunkEnd = idx + 1
+				continue
+			}
+			if idx-hunkEnd >= 2*diffContext {
+				break
+			}
+		}
+		hunkEnd = min(hunkEnd+diffContext, len(ops))
+
+		aStart, bStart, aLen, bLen := ops[hunkStart].aLine, ops[hunkStart].bLine, 0, 0
+		var body strings.Builder
+		for _, op := range ops[hunkStart:hunkEnd] {
+			switch op.kind {
+			case ' ':
+				aLen++
+				bLen++
+			case '-':
+				aLen++
+			case '+':
+				bLen++
+			}
+			fmt.Fprintf(&body, "%c%s\n", op.kind, op.line)
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n%s", aStart+1, aLen, bStart+1, bLen, body.String())
+		start = hunkEnd
+	}
+	return out.String()
+}
+
+type diffOp struct {
+	kind  byte
+	line  string
+	aLine int
+	bLine int
+}
+
+// diffLines computes a line based edit script from the longest common subsequence of a and b.
+func diffLines(a []string, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], aLine: i, bLine: j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: a[i], aLine: i, bLine: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j], aLine: i, bLine: j})
+			j++
+		}
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
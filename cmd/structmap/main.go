@@ -1,31 +1,47 @@
 package main
 
 import (
+	"errors"
 	"flag"
-	"go/format"
+	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
 
-	"github.com/dkowalsky92/structmap/internal/generator"
+	"github.com/dkowalsky92/structmap"
 	"gopkg.in/yaml.v3"
 )
 
 func main() {
-	configFile := flag.String("config", "", "YAML config file")
-	conversionsFile := flag.String("conversions", "", "YAML conversions file")
+	configFile := flag.String("config", "", "YAML config file, - reads it from stdin")
+	conversionsFile := flag.String("conversions", "", "YAML conversions file, - reads it from stdin, optional when the config defines conversions")
+	verbose := flag.Bool("v", false, "print a summary of how dest fields were mapped")
+	veryVerbose := flag.Bool("vv", false, "like -v, and also dump the extracted fields and the generated code")
+	trace := flag.Bool("trace", false, "log every conversion each field was compared against and why it didn't match")
+	tests := flag.Bool("tests", false, "also write a table-driven test stub per mapping, existing test files are kept")
+	plan := flag.Bool("plan", false, "print how every dest field of every mapping will be sourced instead of writing the output files")
+	check := flag.Bool("check", false, "verify the output files are up to date instead of writing them, print a diff and exit 1 otherwise")
+	initTypes := flag.Bool("init", false, "write a starter config and conversions file mapping the two types given as arguments, <import path>.<type>, to -config and -conversions (default config.yaml and conversions.yaml)")
 	flag.Parse()
 
+	if *initTypes {
+		if err := scaffold(flag.Args(), *configFile, *conversionsFile); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if *configFile == "" {
 		log.Fatal("usage: structmap -config config.yaml")
 	}
-
-	if *conversionsFile == "" {
-		log.Fatal("usage: structmap -conversions conversions.yaml")
+	if *configFile == "-" && *conversionsFile == "-" {
+		log.Fatal("only one of -config and -conversions can be read from stdin")
 	}
 
-	var cfg generator.Config
-	raw, err := os.ReadFile(*configFile)
+	var cfg structmap.Config
+	raw, err := readInput(*configFile)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -33,43 +49,153 @@ func main() {
 		log.Fatal(err)
 	}
 
-	var conversions generator.Conversions
-	raw, err = os.ReadFile(*conversionsFile)
-	if err != nil {
-		log.Fatal(err)
+	if *veryVerbose {
+		cfg.LogLevel = structmap.LogLevelDebug
+	} else if *verbose && !cfg.LogsAt(structmap.LogLevelInfo) {
+		cfg.LogLevel = structmap.LogLevelInfo
+	}
+
+	if *trace {
+		cfg.TraceConversions = true
+	}
+	if *tests {
+		cfg.GenerateTests = true
 	}
-	if err := yaml.Unmarshal(raw, &conversions); err != nil {
+
+	if *conversionsFile == "" && len(cfg.Conversions) == 0 {
+		log.Fatal("usage: structmap -conversions conversions.yaml, or define conversions in the config")
+	}
+
+	var conversions structmap.Conversions
+	if *conversionsFile != "" {
+		raw, err = readInput(*conversionsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := yaml.Unmarshal(raw, &conversions); err != nil {
+			log.Fatal(err)
+		}
+		includePath := *conversionsFile
+		if includePath == "-" {
+			includePath = ""
+		}
+		conversions, err = structmap.ResolveIncludes(conversions, includePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := (structmap.Conversions{Conversions: cfg.AllConversions(conversions)}).Validate(); err != nil {
 		log.Fatal(err)
 	}
 
-	generator := generator.NewGenerator(cfg, conversions)
-	code, err := generator.Generate()
+	files, report, err := structmap.GenerateFiles(cfg, conversions)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if *plan {
+		fmt.Print(report)
+		return
+	}
 
-	formattedCode, err := format.Source([]byte(code))
+	outDir := cfg.OutDir()
+	fileMode, err := cfg.OutFileMode()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	if cfg.Debug {
-		log.Printf("Generated code:\n%s", code)
+	dirMode, err := cfg.OutDirMode()
+	if err != nil {
+		log.Fatal(err)
 	}
+	if *check {
+		if !checkFiles(outDir, files) {
+			os.Exit(1)
+		}
+		return
+	}
+	for _, file := range files {
+		if cfg.LogsAt(structmap.LogLevelDebug) {
+			log.Printf("Generated code for %s:\n%s", file.Name, file.Code)
+		}
+		outputPath := filepath.Join(outDir, file.Name)
+		if file.Stub {
+			if _, err := os.Stat(outputPath); err == nil {
+				continue
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				log.Fatal(err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(outputPath), dirMode); err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(outputPath, []byte(file.Code), fileMode); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
 
-	outFilePath := cfg.OutFilePath
-	if outFilePath == "" {
-		outFilePath = "."
+// scaffold writes the starter files of -init, keeping any file that already exists.
+func scaffold(args []string, configFile string, conversionsFile string) error {
+	if len(args) != 2 {
+		return errors.New("usage: structmap -init github.com/acme/app/models.User github.com/acme/app/api.UserDTO")
 	}
-	outFileName := cfg.OutFileName
-	if outFileName == "" {
-		outFileName = "structmap.gen.go"
+	if configFile == "" {
+		configFile = "config.yaml"
 	}
-	outputPath := filepath.Join(outFilePath, outFileName)
-	if err := os.MkdirAll(outFilePath, 0755); err != nil {
-		log.Fatal(err)
+	if conversionsFile == "" {
+		conversionsFile = "conversions.yaml"
 	}
-	if err := os.WriteFile(outputPath, formattedCode, 0644); err != nil {
-		log.Fatal(err)
+	if configFile == "-" || conversionsFile == "-" {
+		return errors.New("-init writes files, -config and -conversions can't be -")
+	}
+	config, conversions, err := structmap.Scaffold(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	files := []struct{ name, content string }{{configFile, config}, {conversionsFile, conversions}}
+	for _, file := range files {
+		if _, err := os.Stat(file.name); err == nil {
+			return fmt.Errorf("%s already exists", file.name)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	for _, file := range files {
+		if err := os.WriteFile(file.name, []byte(file.content), 0o644); err != nil {
+			return err
+		}
+		log.Printf("wrote %s", file.name)
+	}
+	return nil
+}
+
+// readInput reads the named file, or stdin when name is -.
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+func checkFiles(outDir string, files []structmap.File) bool {
+	upToDate := true
+	for _, file := range files {
+		if file.Stub {
+			continue
+		}
+		outputPath := filepath.Join(outDir, file.Name)
+		existing, err := os.ReadFile(outputPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatal(err)
+		}
+		if diff := unifiedDiff(outputPath, string(existing), outputPath+" (generated)", file.Code); diff != "" {
+			if existing == nil {
+				log.Printf("%s is missing", outputPath)
+			} else {
+				log.Printf("%s is out of date", outputPath)
+			}
+			fmt.Print(diff)
+			upToDate = false
+		}
 	}
+	return upToDate
 }
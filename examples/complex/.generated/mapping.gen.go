@@ -1,14 +1,15 @@
 // Code generated by structmap; DO NOT EDIT.
+
 package mapping
 
 import (
-	ref2 "github.com/dkowalsky92/structmap/examples/complex/models1"
-	ref3 "github.com/dkowalsky92/structmap/examples/complex/models2"
-	ref1 "github.com/google/uuid"
+	ref1 "github.com/dkowalsky92/structmap/examples/complex/models1"
+	ref2 "github.com/dkowalsky92/structmap/examples/complex/models2"
+	ref3 "github.com/google/uuid"
 )
 
-// MapUserToUserDTO copies User → UserDTO
-func MapUserToUserDTO(src ref2.User, about *string) (dst ref3.UserDTO) {
+// MapUserToUserDTO copies models1.User → models2.UserDTO
+func MapUserToUserDTO(src ref1.User, about *string) (dst ref2.UserDTO) {
 	dst.Hobbies = src.Hobbies
 	dst.Interests = src.Interests
 	dst.ID = src.ID.String()
@@ -21,11 +22,14 @@ func MapUserToUserDTO(src ref2.User, about *string) (dst ref3.UserDTO) {
 	return
 }
 
-// MapUserDTOToUser copies UserDTO → User
-func MapUserDTOToUser(src ref3.UserDTO) (dst ref2.User, err error) {
+// MapUserDTOToUser copies models2.UserDTO → models1.User
+func MapUserDTOToUser(src ref2.UserDTO) (dst ref1.User, err error) {
 	dst.Hobbies = src.Hobbies
 	dst.Interests = src.Interests
-	dst.ID, err = ref1.Parse(src.ID)
+	dst.ID, err = ref3.Parse(src.ID)
+	if err != nil {
+		return
+	}
 	dst.FirstName = *src.Name
 	dst.Age = src.Age
 	dst.UserHeight = *src.Height
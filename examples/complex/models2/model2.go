@@ -1,7 +1,9 @@
 package models2
 
+type Hobbies []string
+
 type DescriptionDTO struct {
-	Hobbies   []string `json:"hobby"`
+	Hobbies   Hobbies  `json:"hobby"`
 	Interests []string `json:"interests"`
 }
 
@@ -1,4 +1,5 @@
 // Code generated by structmap; DO NOT EDIT.
+
 package main
 
 import (
@@ -6,7 +7,7 @@ import (
 	ref2 "github.com/dkowalsky92/structmap/examples/simple/models2"
 )
 
-// MapUserToUserDTO copies User → UserDTO
+// MapUserToUserDTO copies models1.User → models2.UserDTO
 func MapUserToUserDTO(src ref1.User) (dst ref2.UserDTO) {
 	dst.ID = src.ID
 	dst.Name = src.Name
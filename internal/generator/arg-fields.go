@@ -0,0 +1,63 @@
+package generator
+
+import "strings"
+
+// matchedStruct is the struct whose fields a match_fields arg offers, the pointed-to type when
+// the arg is a pointer.
+func (a AdditionalArg) matchedStruct() TypeWithImportsTemplate {
+	t := a.TypeWithImportsTemplate
+	t.TypeTemplate = strings.TrimPrefix(strings.TrimSpace(t.TypeTemplate), "*")
+	return t
+}
+
+// extractArgFields loads the fields of the mapping's match_fields args, keyed by arg name.
+func (g *Generator) extractArgFields(mapping Mapping) (map[string][]FieldDefinition, error) {
+	argFields := map[string][]FieldDefinition{}
+	for _, arg := range mapping.FuncAdditionalArgs {
+		if !arg.MatchFields {
+			continue
+		}
+		fields, err := g.extractTypeFields(arg.matchedStruct())
+		if err != nil {
+			return nil, err
+		}
+		argFields[arg.Name] = fields
+	}
+	return argFields, nil
+}
+
+// findArgField matches dest against the fields of the match_fields args, in declaration order,
+// by name and then by the mapping's tags like the fields of src. The match is returned as an
+// arg reading the field, e.g. "meta.RequestID".
+func (g *Generator) findArgField(mapping Mapping, dest FieldDefinition, tags []string) *AdditionalArg {
+	for _, arg := range mapping.FuncAdditionalArgs {
+		if !arg.MatchFields || arg.source {
+			continue
+		}
+		fields, _ := g.GetFields(arg.matchedStruct().key())
+		if field, ok := matchArgField(fields, dest, tags); ok {
+			return &AdditionalArg{Name: arg.Name + "." + field.Name, DestField: dest.Name, TypeWithImportsTemplate: field.TypeWithImportsTemplate}
+		}
+	}
+	return nil
+}
+
+func matchArgField(fields []FieldDefinition, dest FieldDefinition, tags []string) (FieldDefinition, bool) {
+	for _, field := range fields {
+		if field.Name == dest.Name {
+			return field, true
+		}
+	}
+	for _, tag := range tags {
+		destTag := tagValue(dest.Tag, tag)
+		if destTag == "" {
+			continue
+		}
+		for _, field := range fields {
+			if tagValue(field.Tag, tag) == destTag {
+				return field, true
+			}
+		}
+	}
+	return FieldDefinition{}, false
+}
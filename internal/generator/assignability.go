@@ -0,0 +1,263 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strings"
+)
+
+// checkAssignable fails generation when the source can't be assigned to the dest field and no
+// conversion applies, instead of leaving a direct assignment that doesn't compile. Only mismatches
+// visible in the underlying types are reported, anything else is left to the compiler.
+func (g *Generator) checkAssignable(mapping Mapping, sourceName string, sourceType TypeWithImportsTemplate, dest FieldDefinition) error {
+	if sourceType.Equals(dest.TypeWithImportsTemplate) || dest.Kind == FieldKindInterface || dest.Kind.Unsupported() {
+		return nil
+	}
+	if mapping.ZeroToNil && pointerTo(sourceType, dest.TypeWithImportsTemplate) {
+		return nil
+	}
+	if conversion, _ := g.findConversion(sourceType, dest.TypeWithImportsTemplate, dest.Name, mapping); conversion != nil {
+		return nil
+	}
+	if conversion := g.findForwardOnlyConversion(sourceType, dest.TypeWithImportsTemplate, dest.Name, mapping); conversion != nil {
+		return fmt.Errorf("field %s: the conversion %s → %s has no reverse_conversion, but %s maps %s → %s, add a reverse_conversion or a conversion for this direction", dest.Name, conversion.SourceType, conversion.DestType, sourceName, sourceType.ExecuteTemplate(g.importManager), dest.ExecuteTemplate(g.importManager))
+	}
+	if _, sourceElem, ok := arrayType(sourceType); ok {
+		if _, destElem, ok := arrayType(dest.TypeWithImportsTemplate); ok {
+			// arrays are copied element by element, so it's the elements that must be assignable
+			elemDest := dest
+			elemDest.TypeWithImportsTemplate = destElem
+			return g.checkAssignable(mapping, sourceName+"[i]", sourceElem, elemDest)
+		}
+	}
+	if _, ok := g.sliceElements(mapping, sourceType, dest); ok {
+		return nil
+	}
+	if g.isNumericWidening(sourceType, dest.TypeWithImportsTemplate) || g.needsTypeConversion(sourceType, dest.TypeWithImportsTemplate) {
+		return nil
+	}
+	if !g.incompatibleUnderlying(sourceType, dest.TypeWithImportsTemplate) {
+		return nil
+	}
+	return fmt.Errorf("field %s: %s has type %s, which can't be assigned to %s, add a conversion for this type pair", dest.Name, sourceName, sourceType.ExecuteTemplate(g.importManager), dest.ExecuteTemplate(g.importManager))
+}
+
+// findForwardOnlyConversion returns a conversion from dest to source that has no reverse template,
+// so it can't be used in the direction of this field.
+func (g *Generator) findForwardOnlyConversion(sourceType TypeWithImportsTemplate, destType TypeWithImportsTemplate, fieldName string, mapping Mapping) *Conversion {
+	for idx, conv := range append(append([]Conversion{}, mapping.CustomConversions...), g.conversions.Conversions...) {
+		if conv.ReverseConversion.defined() || (conv.scoped() && !conv.inScope(fieldName, g.fieldTags)) {
+			continue
+		}
+		if idx >= len(mapping.CustomConversions) && g.conversionDisabled(conv, mapping) {
+			continue
+		}
+		if conv.GetSourceTypeWithImportsTemplate().Equals(destType) && conv.GetDestTypeWithImportsTemplate().Equals(sourceType) {
+			return &conv
+		}
+	}
+	return nil
+}
+
+// incompatibleUnderlying reports whether the underlying types rule out an assignment: they're
+// made of predeclared types only and differ, or they're different kinds of type, say a pointer
+// and a struct. Interfaces and types that can't be resolved are never reported.
+func (g *Generator) incompatibleUnderlying(sourceType TypeWithImportsTemplate, destType TypeWithImportsTemplate) bool {
+	sourceUnderlying, ok := g.underlyingType(sourceType)
+	if !ok || opaqueUnderlying(sourceUnderlying) {
+		return false
+	}
+	destUnderlying, ok := g.underlyingType(destType)
+	if !ok || opaqueUnderlying(destUnderlying) {
+		return false
+	}
+	if predeclaredOnly(sourceUnderlying) && predeclaredOnly(destUnderlying) {
+		// any is an alias, so map[string]any and map[string]interface{} are identical
+		anyAlias := strings.NewReplacer("interface{}", "any")
+		return anyAlias.Replace(renderExpr(sourceUnderlying)) != anyAlias.Replace(renderExpr(destUnderlying))
+	}
+	return reflect.TypeOf(sourceUnderlying) != reflect.TypeOf(destUnderlying)
+}
+
+func opaqueUnderlying(expression ast.Expr) bool {
+	switch e := expression.(type) {
+	case *ast.InterfaceType:
+		return true
+	case *ast.Ident:
+		typeName, ok := types.Universe.Lookup(e.Name).(*types.TypeName)
+		if !ok {
+			return true
+		}
+		_, basic := typeName.Type().(*types.Basic)
+		return !basic
+	}
+	return false
+}
+
+// positionallyAssignable reports whether a field paired by position can be assigned without
+// the user having matched it explicitly: the types are identical, a conversion applies, or the
+// generator converts between them on its own.
+func (g *Generator) positionallyAssignable(source FieldDefinition, dest FieldDefinition, mapping Mapping) bool {
+	if source.Equals(dest.TypeWithImportsTemplate) {
+		return true
+	}
+	if conversion, _ := g.findConversion(source.TypeWithImportsTemplate, dest.TypeWithImportsTemplate, dest.Name, mapping); conversion != nil {
+		return true
+	}
+	if _, _, ok := arrayType(source.TypeWithImportsTemplate); ok {
+		_, _, ok = arrayType(dest.TypeWithImportsTemplate)
+		return ok
+	}
+	return g.isNumericWidening(source.TypeWithImportsTemplate, dest.TypeWithImportsTemplate) || g.needsTypeConversion(source.TypeWithImportsTemplate, dest.TypeWithImportsTemplate)
+}
+
+func (g *Generator) arrayAssignment(
+	mapping Mapping,
+	sourceExpr string,
+	sourceLen string,
+	sourceElem TypeWithImportsTemplate,
+	destExpr string,
+	destLen string,
+	destElem TypeWithImportsTemplate,
+	dest FieldDefinition,
+) (string, bool, error) {
+	renderedSourceLen := NewTypeWithImportsTemplate(sourceLen, sourceElem.Imports).ExecuteTemplate(g.importManager)
+	renderedDestLen := NewTypeWithImportsTemplate(destLen, destElem.Imports).ExecuteTemplate(g.importManager)
+	if renderedSourceLen != renderedDestLen {
+		return "", false, fmt.Errorf("array length mismatch for field %s: [%s] vs [%s]", dest.Name, renderedSourceLen, renderedDestLen)
+	}
+
+	elemDest := dest
+	elemDest.TypeWithImportsTemplate = destElem
+	conversion, isReverse := g.findConversion(sourceElem, destElem, dest.Name, mapping)
+	if conversion == nil && !g.elementsAssignable(sourceElem, destElem) {
+		return "", false, fmt.Errorf("field %s: array elements of type %s can't be assigned to %s, add a conversion for this type pair", dest.Name, sourceElem.ExecuteTemplate(g.importManager), destElem.ExecuteTemplate(g.importManager))
+	}
+	assignment, hasError := g.assignmentWithConversion(mapping, sourceExpr+"[i]", sourceElem, destExpr+"[i]", elemDest, conversion, isReverse)
+	return fmt.Sprintf(`for i := 0; i < %s; i++ {
+		%s
+	}`, renderedDestLen, assignment), hasError, nil
+}
+
+// elementsAssignable reports whether array elements are copied without a conversion: the types
+// are identical, the generator converts between them on its own, or the dest is an interface.
+func (g *Generator) elementsAssignable(sourceElem TypeWithImportsTemplate, destElem TypeWithImportsTemplate) bool {
+	if sourceElem.Equals(destElem) || g.isNumericWidening(sourceElem, destElem) || g.needsTypeConversion(sourceElem, destElem) {
+		return true
+	}
+	underlying, ok := g.underlyingType(destElem)
+	if !ok {
+		return false
+	}
+	switch e := underlying.(type) {
+	case *ast.InterfaceType:
+		return true
+	case *ast.Ident:
+		return e.Name == "any" || e.Name == "error"
+	}
+	return false
+}
+
+// sliceElementPair describes how the elements of two differing slice types are mapped.
+type sliceElementPair struct {
+	source        TypeWithImportsTemplate
+	dest          TypeWithImportsTemplate
+	sourcePointer bool
+	destPointer   bool
+	conversion    *Conversion
+	isReverse     bool
+}
+
+// sliceElements pairs the elements of a []A → []B field. A conversion between the element types
+// themselves wins, otherwise pointers are stripped from both sides and the pointee types must
+// have a conversion or be assignable as they are.
+func (g *Generator) sliceElements(mapping Mapping, sourceType TypeWithImportsTemplate, dest FieldDefinition) (sliceElementPair, bool) {
+	sourceElem, ok := sliceType(sourceType)
+	if !ok {
+		return sliceElementPair{}, false
+	}
+	destElem, ok := sliceType(dest.TypeWithImportsTemplate)
+	if !ok {
+		return sliceElementPair{}, false
+	}
+	if conversion, isReverse := g.findConversion(sourceElem, destElem, dest.Name, mapping); conversion != nil {
+		return sliceElementPair{source: sourceElem, dest: destElem, conversion: conversion, isReverse: isReverse}, true
+	}
+	pair := sliceElementPair{
+		source:        NewTypeWithImportsTemplate(strings.TrimPrefix(sourceElem.TypeTemplate, "*"), sourceElem.Imports),
+		dest:          NewTypeWithImportsTemplate(strings.TrimPrefix(destElem.TypeTemplate, "*"), destElem.Imports),
+		sourcePointer: strings.HasPrefix(sourceElem.TypeTemplate, "*"),
+		destPointer:   strings.HasPrefix(destElem.TypeTemplate, "*"),
+	}
+	pair.conversion, pair.isReverse = g.findConversion(pair.source, pair.dest, dest.Name, mapping)
+	if pair.conversion == nil && !pair.source.Equals(pair.dest) && !g.isNumericWidening(pair.source, pair.dest) && !g.needsTypeConversion(pair.source, pair.dest) {
+		return sliceElementPair{}, false
+	}
+	return pair, true
+}
+
+// sliceAssignment maps a slice element by element into a fresh slice, nil stays nil. Nil elements
+// of a []*A source are left at the zero value, and elements of a []*B dest point to a copy.
+func (g *Generator) sliceAssignment(mapping Mapping, sourceExpr string, destExpr string, dest FieldDefinition, elements sliceElementPair) (string, bool) {
+	sourceItem := sourceExpr + "[i]"
+	if elements.sourcePointer {
+		// templates may apply selectors to {{ .Source }}, so the dereference is parenthesized
+		sourceItem = "*" + sourceItem
+		if elements.conversion != nil {
+			sourceItem = "(" + sourceItem + ")"
+		}
+	}
+	destItem := destExpr + "[i]"
+	if elements.destPointer {
+		destItem = "item"
+	}
+	elemDest := dest
+	elemDest.TypeWithImportsTemplate = elements.dest
+	assignment, hasError := g.assignmentWithConversion(mapping, sourceItem, elements.source, destItem, elemDest, elements.conversion, elements.isReverse)
+	if elements.destPointer {
+		assignment = fmt.Sprintf(`var item %s
+		%s
+		%s[i] = &item`, elements.dest.ExecuteTemplate(g.importManager), assignment, destExpr)
+	}
+	if elements.sourcePointer {
+		assignment = fmt.Sprintf(`if %s[i] != nil {
+			%s
+		}`, sourceExpr, assignment)
+	}
+	return fmt.Sprintf(`if %s != nil {
+		%s = make(%s, len(%s))
+		for i := range %s {
+			%s
+		}
+	}`, sourceExpr, destExpr, dest.ExecuteTemplate(g.importManager), sourceExpr, sourceExpr, assignment), hasError
+}
+
+func sliceType(t TypeWithImportsTemplate) (TypeWithImportsTemplate, bool) {
+	elem, ok := strings.CutPrefix(strings.TrimSpace(t.TypeTemplate), "[]")
+	if !ok {
+		return TypeWithImportsTemplate{}, false
+	}
+	return NewTypeWithImportsTemplate(elem, t.Imports), true
+}
+
+func arrayType(t TypeWithImportsTemplate) (string, TypeWithImportsTemplate, bool) {
+	typ := t.TypeTemplate
+	if !strings.HasPrefix(typ, "[") || strings.HasPrefix(typ, "[]") {
+		return "", TypeWithImportsTemplate{}, false
+	}
+	depth := 0
+	for idx, r := range typ {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return typ[1:idx], NewTypeWithImportsTemplate(typ[idx+1:], t.Imports), true
+			}
+		}
+	}
+	return "", TypeWithImportsTemplate{}, false
+}
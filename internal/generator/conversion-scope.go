@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+// fieldTagPattern matches the field_tag of a conversion, a tag key optionally followed by the
+// value it must have, written like in a struct tag: sensitive or sensitive:"true".
+var fieldTagPattern = regexp.MustCompile(`^([A-Za-z0-9_]+)(:"(?:[^"\\]|\\.)*")?$`)
+
+// scoped reports whether the conversion only applies to some fields, picked by name or tag.
+func (c Conversion) scoped() bool {
+	return c.FieldName != "" || c.FieldTag != ""
+}
+
+// inScope reports whether a scoped conversion applies to the dest field named fieldName, whose
+// own and source field's struct tags are tags. Both field_name and field_tag must match when set.
+func (c Conversion) inScope(fieldName string, tags []string) bool {
+	if c.FieldName != "" && c.FieldName != fieldName {
+		return false
+	}
+	if c.FieldTag == "" {
+		return true
+	}
+	match := fieldTagPattern.FindStringSubmatch(c.FieldTag)
+	if match == nil {
+		return false
+	}
+	for _, tag := range tags {
+		value, ok := reflect.StructTag(tag).Lookup(match[1])
+		if !ok {
+			continue
+		}
+		if match[2] == "" {
+			return true
+		}
+		if want, err := strconv.Unquote(match[2][1:]); err == nil && value == want {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Conversion) validateFieldTag() error {
+	if c.FieldTag != "" && !fieldTagPattern.MatchString(c.FieldTag) {
+		return fmt.Errorf("invalid field_tag %q, must be a tag key optionally followed by a quoted value, e.g. sensitive:\"true\"", c.FieldTag)
+	}
+	return nil
+}
+
+// scopeDescription describes which fields a scoped conversion applies to, for logs and reports.
+func scopeDescription(fieldName string, fieldTag string) string {
+	switch {
+	case fieldName != "" && fieldTag != "":
+		return fmt.Sprintf("field %s tagged %s", fieldName, fieldTag)
+	case fieldTag != "":
+		return "fields tagged " + fieldTag
+	}
+	return "field " + fieldName
+}
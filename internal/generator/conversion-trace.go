@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tracesConversions reports whether conversion lookups are logged, trace_conversions or the debug level enable it.
+func (c Config) tracesConversions() bool {
+	return c.TraceConversions || c.LogsAt(LogLevelDebug)
+}
+
+// conversionMismatch returns why conv doesn't convert source to dest, in reverse when isReverse is
+// set, or "" when it does.
+func conversionMismatch(conv Conversion, source, dest TypeWithImportsTemplate, isReverse bool) string {
+	convSource, convDest := conv.GetSourceTypeWithImportsTemplate(), conv.GetDestTypeWithImportsTemplate()
+	if isReverse {
+		if !conv.ReverseConversion.defined() {
+			return "no reverse_conversion"
+		}
+		convSource, convDest = convDest, convSource
+	}
+	if !convSource.Equals(source) {
+		return fmt.Sprintf("source %s != %s", convSource.key(), source.key())
+	}
+	if !convDest.Equals(dest) {
+		return fmt.Sprintf("dest %s != %s", convDest.key(), dest.key())
+	}
+	return ""
+}
+
+// conversionTrace collects the comparisons of a single conversion lookup.
+type conversionTrace struct {
+	lines   []string
+	matched string
+}
+
+func (t *conversionTrace) addf(format string, v ...any) {
+	if t != nil {
+		t.lines = append(t.lines, fmt.Sprintf(format, v...))
+	}
+}
+
+// compare records the outcome of comparing the conversion described by label in both directions,
+// forward and reverse being the mismatch reasons.
+func (t *conversionTrace) compare(label, forward, reverse string) {
+	if t == nil {
+		return
+	}
+	var result string
+	switch {
+	case forward == "":
+		result, t.matched = "match", label
+	case reverse == "":
+		result, t.matched = "reverse match", "reverse of "+label
+	case forward == reverse:
+		result = "no match: " + forward
+	default:
+		result = fmt.Sprintf("no match: %s; reverse: %s", forward, reverse)
+	}
+	t.addf("  %s: %s", label, result)
+}
+
+// newConversionTrace starts a trace for the lookup of a conversion for fieldName, nil when tracing is off.
+// Lookups repeated for the same field and types are traced once.
+func (g *Generator) newConversionTrace(source, dest TypeWithImportsTemplate, fieldName string) *conversionTrace {
+	if !g.config.tracesConversions() {
+		return nil
+	}
+	mappingKey := ""
+	if len(g.mappingPath) > 0 {
+		mappingKey = g.mappingPath[len(g.mappingPath)-1]
+	}
+	key := strings.Join([]string{mappingKey, fieldName, source.key(), dest.key()}, "\x00")
+	if g.tracedConversions[key] {
+		return nil
+	}
+	g.tracedConversions[key] = true
+	t := &conversionTrace{}
+	t.addf("conversion lookup for field %s of %s: %s → %s", fieldName, mappingKey, source.key(), dest.key())
+	return t
+}
+
+// conversionLabel describes the conversion at idx of the given list for the trace.
+func (g *Generator) conversionLabel(list string, idx int, conv Conversion) string {
+	label := fmt.Sprintf("%s[%d] %s → %s", list, idx, conv.GetSourceTypeWithImportsTemplate().GetQualifiedType(g.packageName), conv.GetDestTypeWithImportsTemplate().GetQualifiedType(g.packageName))
+	if conv.scoped() {
+		label += fmt.Sprintf(" (%s)", scopeDescription(conv.FieldName, conv.FieldTag))
+	}
+	return label
+}
+
+func (g *Generator) logConversionTrace(t *conversionTrace) {
+	if t == nil {
+		return
+	}
+	if t.matched == "" {
+		t.matched = "no conversion"
+	}
+	t.addf("  result: %s", t.matched)
+	g.logger().Printf("%s", strings.Join(t.lines, "\n"))
+}
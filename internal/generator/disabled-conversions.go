@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// conversionPair names a conversion as written in disable_conversions, e.g. "time.Time → string".
+func (g *Generator) conversionPair(conv Conversion) string {
+	return fmt.Sprintf("%s → %s", conv.GetSourceTypeWithImportsTemplate().GetQualifiedType(g.packageName), conv.GetDestTypeWithImportsTemplate().GetQualifiedType(g.packageName))
+}
+
+// normalizeConversionPair accepts both arrows and any spacing around them.
+func normalizeConversionPair(pair string) string {
+	source, dest, ok := strings.Cut(strings.ReplaceAll(pair, "->", "→"), "→")
+	if !ok {
+		return strings.TrimSpace(pair)
+	}
+	return strings.TrimSpace(source) + " → " + strings.TrimSpace(dest)
+}
+
+// conversionDisabled reports whether the mapping opts out of the shared conversion, by its id
+// or its type pair.
+func (g *Generator) conversionDisabled(conv Conversion, mapping Mapping) bool {
+	if len(mapping.DisableConversions) == 0 {
+		return false
+	}
+	pair := g.conversionPair(conv)
+	return slices.ContainsFunc(mapping.DisableConversions, func(entry string) bool {
+		return (conv.ID != "" && strings.TrimSpace(entry) == conv.ID) || normalizeConversionPair(entry) == pair
+	})
+}
+
+// validateDisabledConversions checks that every disable_conversions entry matches a shared
+// conversion, so a typo doesn't silently leave the conversion enabled.
+func (g *Generator) validateDisabledConversions(mapping Mapping) error {
+	var errs []error
+	for _, entry := range mapping.DisableConversions {
+		matched := slices.ContainsFunc(g.conversions.Conversions, func(conv Conversion) bool {
+			return g.conversionDisabled(conv, Mapping{DisableConversions: []string{entry}})
+		})
+		if !matched {
+			errs = append(errs, fmt.Errorf("disable_conversions entry %q matches no conversion id or type pair", entry))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EnumValue pairs a source value of an enum conversion with the dest value it converts to. Both
+// are Go expressions, typically constants, that may use the conversion's {{ .ImportN }}.
+type EnumValue struct {
+	Source string `yaml:"source"`
+	Dest   string `yaml:"dest"`
+}
+
+// validateEnum checks the values of an enum conversion, which replace the conversion templates.
+func (c Conversion) validateEnum() error {
+	if len(c.Values) == 0 || c.enumExpanded {
+		return nil
+	}
+	if c.Conversion.defined() || c.ReverseConversion.defined() {
+		return fmt.Errorf("values generate the conversion templates, tmpl and use_func can't be set")
+	}
+	var sources []string
+	for _, value := range c.Values {
+		if strings.TrimSpace(value.Source) == "" || strings.TrimSpace(value.Dest) == "" {
+			return fmt.Errorf("every value needs a source and a dest")
+		}
+		if slices.Contains(sources, value.Source) {
+			return fmt.Errorf("duplicate source value %s", value.Source)
+		}
+		sources = append(sources, value.Source)
+	}
+	return nil
+}
+
+// expandEnum turns the values of an enum conversion into switch templates for both directions,
+// so it's generated like any other conversion assigning {{ .Ok }}. Values without a match fail
+// the mapping unless on_not_ok says otherwise. Several source values may convert to the same dest
+// value, converting back picks the first of them.
+func (c Conversion) expandEnum() Conversion {
+	if len(c.Values) == 0 || c.enumExpanded {
+		return c
+	}
+	var forward, reverse []string
+	var reversed []string
+	for _, value := range c.Values {
+		forward = append(forward, enumCase(value.Source, value.Dest))
+		if !slices.Contains(reversed, value.Dest) {
+			reversed = append(reversed, value.Dest)
+			reverse = append(reverse, enumCase(value.Dest, value.Source))
+		}
+	}
+	c.Conversion = c.Conversion.enumTemplate(forward)
+	c.ReverseConversion = c.ReverseConversion.enumTemplate(reverse)
+	c.enumExpanded = true
+	return c
+}
+
+func enumCase(match string, result string) string {
+	return fmt.Sprintf("case %s:\n\t{{ .Dest }}, {{ .Ok }} = %s, true", match, result)
+}
+
+func (t ConversionTemplate) enumTemplate(cases []string) ConversionTemplate {
+	t.Tmpl = fmt.Sprintf("switch {{ .Source }} {\n%s\n}", strings.Join(cases, "\n"))
+	if t.OnNotOk == "" {
+		t.OnNotOk = NotOkError
+	}
+	return t
+}
+
+// expandEnumConversions returns conversions with every enum conversion expanded.
+func expandEnumConversions(conversions []Conversion) []Conversion {
+	expanded := make([]Conversion, len(conversions))
+	for idx, conversion := range conversions {
+		expanded[idx] = conversion.expandEnum()
+	}
+	return expanded
+}
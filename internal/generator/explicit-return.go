@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+)
+
+// explicitReturns spells out the named results in every bare return of the generated functions,
+// e.g. `return dst, err`, for linters that flag naked returns. Returns inside function literals
+// are left alone, since they belong to a different signature.
+func (g *Generator) explicitReturns(code string) (string, error) {
+	if !g.config.ExplicitReturn {
+		return code, nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", code, parser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated code: %w", err)
+	}
+	for _, decl := range f.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Body == nil || funcDecl.Type.Results == nil {
+			continue
+		}
+		var names []string
+		for _, result := range funcDecl.Type.Results.List {
+			for _, name := range result.Names {
+				names = append(names, name.Name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		ast.Inspect(funcDecl.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.ReturnStmt:
+				if len(n.Results) == 0 {
+					for _, name := range names {
+						n.Results = append(n.Results, &ast.Ident{NamePos: n.Return, Name: name})
+					}
+				}
+			}
+			return true
+		})
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return "", fmt.Errorf("failed to print generated code: %w", err)
+	}
+	return buf.String(), nil
+}
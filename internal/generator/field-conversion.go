@@ -0,0 +1,62 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"text/template"
+)
+
+// validateFieldConversions checks the conversions of custom field mappings, which only apply
+// to mappings of a single source field onto a single dest field.
+func validateFieldConversions(customFieldMappings []CustomFieldMapping) error {
+	var errs []error
+	for _, customFieldMapping := range customFieldMappings {
+		if customFieldMapping.Conversion == nil {
+			continue
+		}
+		field := customFieldMapping.DestField
+		if field == "" {
+			field = customFieldMapping.DestTag
+		}
+		if len(customFieldMapping.SourceFields) > 0 || len(customFieldMapping.DestFields) > 0 {
+			errs = append(errs, fmt.Errorf("custom field mapping for %s: conversion can't be combined with source_fields or dest_fields, use tmpl or tmpls", field))
+			continue
+		}
+		if !customFieldMapping.Conversion.defined() {
+			errs = append(errs, fmt.Errorf("custom field mapping for %s: conversion needs a tmpl or use_func", field))
+			continue
+		}
+		if _, err := template.New("conversion").Parse(customFieldMapping.Conversion.Tmpl); err != nil {
+			errs = append(errs, fmt.Errorf("custom field mapping for %s: invalid conversion template: %w", field, err))
+		}
+		if err := customFieldMapping.Conversion.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("custom field mapping for %s: conversion: %w", field, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fieldConversion turns the conversion of a custom field mapping into a conversion between
+// the types of the mapped fields, which takes precedence over conversions matched by type.
+// The template's imports come first, so {{ .ImportN }} refers to them, followed by the
+// imports of the field types.
+func (c CustomFieldMapping) fieldConversion(sourceType TypeWithImportsTemplate, destType TypeWithImportsTemplate) *Conversion {
+	conversionTemplate := *c.Conversion
+	conversionTemplate.Imports = nil
+	templateImports := c.Conversion.Imports
+	return &Conversion{
+		SourceType: shiftImports(sourceType, len(templateImports)),
+		DestType:   shiftImports(destType, len(templateImports)+len(sourceType.Imports)),
+		Conversion: conversionTemplate,
+		Imports:    append(append(append([]string{}, templateImports...), sourceType.Imports...), destType.Imports...),
+	}
+}
+
+// shiftImports renumbers the import placeholders of t by offset.
+func shiftImports(t TypeWithImportsTemplate, offset int) string {
+	return importPlaceholderPattern.ReplaceAllStringFunc(t.TypeTemplate, func(match string) string {
+		idx, _ := strconv.Atoi(importPlaceholderPattern.FindStringSubmatch(match)[1])
+		return fmt.Sprintf("{{ .Import%d }}", idx+offset)
+	})
+}
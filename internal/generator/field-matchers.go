@@ -0,0 +1,31 @@
+package generator
+
+// FieldMatcher picks the source field of a dest field that custom field mappings, names and
+// tags left unmapped, e.g. from a table of synonyms. Library users register matchers through
+// Config.FieldMatchers.
+type FieldMatcher interface {
+	// MatchField returns one of sources for dest, or false to leave dest to the next matcher.
+	MatchField(dest FieldDefinition, sources []FieldDefinition) (FieldDefinition, bool)
+}
+
+// FieldMatcherFunc adapts a function to FieldMatcher.
+type FieldMatcherFunc func(dest FieldDefinition, sources []FieldDefinition) (FieldDefinition, bool)
+
+func (f FieldMatcherFunc) MatchField(dest FieldDefinition, sources []FieldDefinition) (FieldDefinition, bool) {
+	return f(dest, sources)
+}
+
+// matchWithMatchers asks the matchers in turn for the source of dest. A field that isn't one
+// of the source fields is ignored, since it couldn't be read from src.
+func matchWithMatchers(matchers []FieldMatcher, dest FieldDefinition, sourceFields []FieldDefinition, byName map[string]FieldDefinition) (*FieldDefinition, bool) {
+	for _, matcher := range matchers {
+		field, ok := matcher.MatchField(dest, sourceFields)
+		if !ok {
+			continue
+		}
+		if source, ok := byName[field.Name]; ok {
+			return &source, true
+		}
+	}
+	return nil, false
+}
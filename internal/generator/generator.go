@@ -1,56 +1,321 @@
 package generator
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/format"
 	"go/parser"
 	"go/token"
-	"html/template"
-	"log"
+	"go/types"
+	"io/fs"
+	"path"
 	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
 
 	"go/printer"
 
 	"github.com/dkowalsky92/structmap/internal/imports"
 	"github.com/dkowalsky92/structmap/internal/packages"
+	"golang.org/x/tools/go/ast/astutil"
 )
 
 type Conversions struct {
 	Conversions []Conversion `yaml:"conversions"`
+	// Include lists further conversions files, see ResolveIncludes.
+	Include []string `yaml:"include,omitempty"`
+}
+
+// Validate parses every conversion template and reports all parse errors at once.
+func (c Conversions) Validate() error {
+	return validateConversionTemplates(c.Conversions)
 }
 
 type Config struct {
-	OutPackageName string    `yaml:"out_package_name"`
-	OutFileName    string    `yaml:"out_file_name,omitempty"`
-	OutFilePath    string    `yaml:"out_file_path,omitempty"`
-	Mappings       []Mapping `yaml:"mappings"`
-	Debug          bool      `yaml:"debug,omitempty"`
+	OutPackageName       string            `yaml:"out_package_name"`
+	OutFileName          string            `yaml:"out_file_name,omitempty"`
+	OutFilePath          string            `yaml:"out_file_path,omitempty"`
+	Mappings             []Mapping         `yaml:"mappings"`
+	Debug                bool              `yaml:"debug,omitempty"`
+	LogLevel             LogLevel          `yaml:"log_level,omitempty"`
+	Logger               Logger            `yaml:"-"`
+	FieldMatchers        []FieldMatcher    `yaml:"-"`
+	Strict               bool              `yaml:"strict,omitempty"`
+	WrapConversionErrors bool              `yaml:"wrap_conversion_errors,omitempty"`
+	SrcName              string            `yaml:"src_name,omitempty"`
+	DstName              string            `yaml:"dst_name,omitempty"`
+	ErrName              string            `yaml:"err_name,omitempty"`
+	AliasPrefix          string            `yaml:"alias_prefix,omitempty"`
+	ErrorType            *ErrorType        `yaml:"error_type,omitempty"`
+	GoVersion            string            `yaml:"go_version,omitempty"`
+	TraceConversions     bool              `yaml:"trace_conversions,omitempty"`
+	ExplicitReturn       bool              `yaml:"explicit_return,omitempty"`
+	FuncNameTemplate     string            `yaml:"func_name_template,omitempty"`
+	IgnorePackageErrors  bool              `yaml:"ignore_package_errors,omitempty"`
+	PackageSources       map[string]string `yaml:"package_sources,omitempty"`
+	SplitFiles           bool              `yaml:"split_files,omitempty"`
+	GenerateDispatcher   bool              `yaml:"generate_dispatcher,omitempty"`
+	GenerateTests        bool              `yaml:"generate_tests,omitempty"`
+	LintDirectives       []string          `yaml:"lint_directives,omitempty"`
+	GeneratedMarker      string            `yaml:"generated_marker,omitempty"`
+	FileMode             string            `yaml:"file_mode,omitempty"`
+	DirMode              string            `yaml:"dir_mode,omitempty"`
+	Conversions          []Conversion      `yaml:"conversions,omitempty"`
+}
+
+// AllConversions returns the conversions defined in the config followed by the given ones,
+// so inline conversions take precedence over the ones from a separate conversions file.
+func (c Config) AllConversions(conversions Conversions) []Conversion {
+	return append(append([]Conversion{}, c.Conversions...), conversions.Conversions...)
+}
+
+type File struct {
+	Name string
+	Code string
+	// Stub marks a file meant to be edited after generation, such as a test skeleton,
+	// so an existing copy should be kept rather than overwritten.
+	Stub bool
+}
+
+func (c Config) OutFile() string {
+	if c.OutFileName != "" {
+		return c.OutFileName
+	}
+	return "structmap.gen.go"
+}
+
+func (c Config) OutDir() string {
+	if c.OutFilePath != "" {
+		return c.OutFilePath
+	}
+	return "."
+}
+
+// OutFileMode returns the permissions of written files, parsed from the octal file_mode (default: 0644).
+func (c Config) OutFileMode() (fs.FileMode, error) {
+	return parseFileMode("file_mode", c.FileMode, 0644)
+}
+
+// OutDirMode returns the permissions of created directories, parsed from the octal dir_mode (default: 0755).
+func (c Config) OutDirMode() (fs.FileMode, error) {
+	return parseFileMode("dir_mode", c.DirMode, 0755)
+}
+
+func parseFileMode(key string, mode string, fallback fs.FileMode) (fs.FileMode, error) {
+	if mode == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseUint(strings.TrimPrefix(mode, "0o"), 8, 32)
+	if err != nil || parsed > uint64(fs.ModePerm) {
+		return 0, fmt.Errorf("invalid %s %q, must be octal permissions such as %#o", key, mode, fallback)
+	}
+	return fs.FileMode(parsed), nil
+}
+
+// Marker returns the comment line that marks files as generated.
+func (c Config) Marker() string {
+	if c.GeneratedMarker == "" {
+		return "// Code generated by structmap; DO NOT EDIT."
+	}
+	marker := strings.TrimSpace(c.GeneratedMarker)
+	if !strings.HasPrefix(marker, "//") {
+		marker = "// " + marker
+	}
+	return marker
+}
+
+var generatedMarkerPattern = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+func (c Config) SrcVar() string {
+	if c.SrcName != "" {
+		return c.SrcName
+	}
+	return "src"
+}
+
+func (c Config) DstVar() string {
+	if c.DstName != "" {
+		return c.DstName
+	}
+	return "dst"
+}
+
+func (c Config) ErrVar() string {
+	if c.ErrName != "" {
+		return c.ErrName
+	}
+	return "err"
+}
+
+// ImportAliasPrefix is the prefix of the numbered aliases given to imports, ref by default.
+func (c Config) ImportAliasPrefix() string {
+	if c.AliasPrefix != "" {
+		return c.AliasPrefix
+	}
+	return "ref"
+}
+
+func (c Config) validateVarNames() error {
+	names := []string{c.SrcVar(), c.DstVar(), c.ErrVar()}
+	for idx, name := range names {
+		if !token.IsIdentifier(name) || name == "_" {
+			return fmt.Errorf("invalid variable name %q, must be a valid Go identifier", name)
+		}
+		for _, other := range names[:idx] {
+			if name == other {
+				return fmt.Errorf("src_name, dst_name and err_name must be distinct, %q is used more than once", name)
+			}
+		}
+	}
+	if prefix := c.ImportAliasPrefix(); !token.IsIdentifier(prefix) || prefix == "_" {
+		return fmt.Errorf("invalid alias_prefix %q, must be a valid Go identifier", prefix)
+	}
+	return nil
 }
 
 type Mapping struct {
-	From                StructDefinition     `yaml:"from"`
+	// From lists the source structs, a single one in the common case. Several sources are passed
+	// as src0, src1, … and their fields are all matched against the dest, see withSourceArgs.
+	From                []StructDefinition   `yaml:"from"`
 	To                  StructDefinition     `yaml:"to"`
 	FuncName            string               `yaml:"func_name,omitempty"`
 	FuncAdditionalArgs  []AdditionalArg      `yaml:"func_additional_args,omitempty"`
 	CustomFieldMappings []CustomFieldMapping `yaml:"custom_field_mappings,omitempty"`
 	CustomConversions   []Conversion         `yaml:"custom_conversions,omitempty"`
 	Tag                 string               `yaml:"tag,omitempty"`
+	Tags                []string             `yaml:"tags,omitempty"`
+	RespectSkipTag      bool                 `yaml:"respect_skip_tag,omitempty"`
+	RespectOmitEmpty    bool                 `yaml:"respect_omitempty,omitempty"`
+	ExplicitDefaults    bool                 `yaml:"explicit_defaults,omitempty"`
+	DeepCopy            bool                 `yaml:"deep_copy,omitempty"`
+	AlwaysError         *bool                `yaml:"always_error,omitempty"`
+	OutPackageName      string               `yaml:"out_package_name,omitempty"`
+	OutFileName         string               `yaml:"out_file_name,omitempty"`
+	InPlace             bool                 `yaml:"in_place,omitempty"`
+	FromConcrete        *StructDefinition    `yaml:"from_concrete,omitempty"`
+	MatchByPosition     bool                 `yaml:"match_by_position,omitempty"`
+	DocumentDropped     bool                 `yaml:"document_dropped,omitempty"`
+	DisableConversions  []string             `yaml:"disable_conversions,omitempty"`
+	AllowSelfMap        bool                 `yaml:"allow_self_map,omitempty"`
+	ZeroToNil           bool                 `yaml:"zero_to_nil,omitempty"`
+}
+
+// sourceStruct is the struct whose fields are read, the concrete type when From is an interface.
+func (m Mapping) sourceStruct() TypeWithImportsTemplate {
+	if m.FromConcrete != nil {
+		concrete := m.FromConcrete.TypeWithImportsTemplate
+		concrete.TypeTemplate = strings.TrimPrefix(strings.TrimSpace(concrete.TypeTemplate), "*")
+		return concrete
+	}
+	return m.primary().TypeWithImportsTemplate
+}
+
+func (m Mapping) MatchTags() []string {
+	if len(m.Tags) > 0 {
+		return m.Tags
+	}
+	if m.Tag != "" {
+		return []string{m.Tag}
+	}
+	return []string{"json"}
+}
+
+func (m Mapping) AdditionalArgNames() []string {
+	names := make([]string, len(m.FuncAdditionalArgs))
+	for idx, arg := range m.FuncAdditionalArgs {
+		names[idx] = arg.Name
+	}
+	return names
 }
 
 type CustomFieldMapping struct {
-	SourceField string `yaml:"source_field,omitempty"`
-	DestField   string `yaml:"dest_field,omitempty"`
-	SourceTag   string `yaml:"source_tag,omitempty"`
-	DestTag     string `yaml:"dest_tag,omitempty"`
-	Tag         string `yaml:"tag,omitempty"`
+	SourceField  string   `yaml:"source_field,omitempty"`
+	SourceFields []string `yaml:"source_fields,omitempty"`
+	DestField    string   `yaml:"dest_field,omitempty"`
+	DestFields   []string `yaml:"dest_fields,omitempty"`
+	SourceTag    string   `yaml:"source_tag,omitempty"`
+	DestTag      string   `yaml:"dest_tag,omitempty"`
+	Tag          string   `yaml:"tag,omitempty"`
+	Joiner       string   `yaml:"joiner,omitempty"`
+	Tmpl         string   `yaml:"tmpl,omitempty"`
+	Tmpls        []string `yaml:"tmpls,omitempty"`
+	Imports      []string `yaml:"imports,omitempty"`
+	OmitEmpty    bool     `yaml:"omit_empty,omitempty"`
+	DefaultOnNil string   `yaml:"default_on_nil,omitempty"`
+	SourceIndex  int      `yaml:"source_index,omitempty"`
+	// Conversion converts the source field into the dest field, in place of any conversion
+	// matching their types, see fieldConversion.
+	Conversion *ConversionTemplate `yaml:"conversion,omitempty"`
+}
+
+// ExecuteDefaultTemplate renders DefaultOnNil, which may use the mapping's imports and additional args.
+func (c *CustomFieldMapping) ExecuteDefaultTemplate(args []string, importManager *imports.ImportManager) (string, error) {
+	data := make(map[string]string)
+	for _, arg := range args {
+		data[arg] = arg
+	}
+	return c.executeTemplate(c.DefaultOnNil, data, importManager, "default_on_nil")
+}
+
+func (c *CustomFieldMapping) ExecuteCompositeTemplate(sourceExprs []string, destExpr string, args []string, importManager *imports.ImportManager) (string, error) {
+	if c.Tmpl == "" {
+		return fmt.Sprintf("%s = %s", destExpr, strings.Join(sourceExprs, fmt.Sprintf(" + %q + ", c.Joiner))), nil
+	}
+	data := make(map[string]string)
+	for _, arg := range args {
+		data[arg] = arg
+	}
+	for idx, sourceExpr := range sourceExprs {
+		data[fmt.Sprintf("Source%d", idx)] = sourceExpr
+	}
+	data["Dest"] = destExpr
+	return c.executeTemplate(c.Tmpl, data, importManager, "composite")
+}
+
+func (c *CustomFieldMapping) ExecuteSplitTemplate(sourceExpr string, destExpr string, destIdx int, args []string, importManager *imports.ImportManager) (string, error) {
+	data := make(map[string]string)
+	for _, arg := range args {
+		data[arg] = arg
+	}
+	data["Source"] = sourceExpr
+	data["Dest"] = destExpr
+	return c.executeTemplate(c.Tmpls[destIdx], data, importManager, "split")
+}
+
+func (c *CustomFieldMapping) executeTemplate(tmplStr string, data map[string]string, importManager *imports.ImportManager, tmplName string) (string, error) {
+	var buf strings.Builder
+	tmpl, err := template.New(tmplName).Parse(tmplStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %w", tmplName, err)
+	}
+	for idx, imp := range c.Imports {
+		data[fmt.Sprintf("Import%d", idx)] = importManager.GetImportAlias(imp)
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute %s template: %w", tmplName, err)
+	}
+	return buf.String(), nil
 }
 
 type AdditionalArg struct {
 	Name                    string `yaml:"name"`
 	DestField               string `yaml:"dest_field"`
+	Position                *int   `yaml:"position,omitempty"`
+	MatchFields             bool   `yaml:"match_fields,omitempty"`
 	TypeWithImportsTemplate `yaml:",inline"`
+	// source marks the args standing for additional sources, see withSourceArgs.
+	source bool
 }
 
 func (a *AdditionalArg) RenderParameter(importManager *imports.ImportManager) string {
@@ -59,16 +324,79 @@ func (a *AdditionalArg) RenderParameter(importManager *imports.ImportManager) st
 }
 
 type Conversion struct {
+	// ID names the conversion for disable_conversions.
+	ID                string             `yaml:"id,omitempty"`
 	SourceType        string             `yaml:"source_type"`
 	DestType          string             `yaml:"dest_type"`
+	SourceTypePattern string             `yaml:"source_type_pattern,omitempty"`
+	DestTypePattern   string             `yaml:"dest_type_pattern,omitempty"`
 	Conversion        ConversionTemplate `yaml:"conversion"`
 	ReverseConversion ConversionTemplate `yaml:"reverse_conversion,omitempty"`
 	Imports           []string           `yaml:"imports"`
+	NilSafe           bool               `yaml:"nil_safe,omitempty"`
+	MatchUnderlying   bool               `yaml:"match_underlying,omitempty"`
+	ApplyToSameType   bool               `yaml:"apply_to_same_type,omitempty"`
+	NeedsContext      bool               `yaml:"needs_context,omitempty"`
+	NeedsSrcStruct    bool               `yaml:"needs_src_struct,omitempty"`
+	FieldName         string             `yaml:"field_name,omitempty"`
+	FieldTag          string             `yaml:"field_tag,omitempty"`
+	// Values make this an enum conversion, generated as a switch over the listed pairs in both
+	// directions instead of from conversion templates.
+	Values []EnumValue `yaml:"values,omitempty"`
+	// enumExpanded is set once Values have been turned into templates, see expandEnum.
+	enumExpanded bool
+	// captures holds the capture groups of the type patterns a conversion was matched with.
+	captures map[string]string
+	// listIdx is the position in the generator's conversions plus one, zero for the custom
+	// conversions of a mapping. It marks the conversion used once its assignment is emitted.
+	listIdx int
 }
 
 type ConversionTemplate struct {
 	Tmpl  string `yaml:"tmpl"`
 	Error bool   `yaml:"error,omitempty"`
+	// OnNotOk is set for templates assigning a second ok result to {{ .Ok }}, such as map
+	// lookups and type assertions, and picks what happens when it's false.
+	OnNotOk NotOkAction `yaml:"on_not_ok,omitempty"`
+	// Default is the value assigned to the dest when ok is false and OnNotOk is default,
+	// the zero value of the dest type when empty.
+	Default string `yaml:"default,omitempty"`
+	// Imports are only imported when this template is used, and are numbered after the
+	// conversion's imports in {{ .ImportN }}.
+	Imports []string `yaml:"imports,omitempty"`
+	// UseFunc names the generated mapper converting the value instead of Tmpl, see useFuncConversion.
+	UseFunc string `yaml:"use_func,omitempty"`
+}
+
+type NotOkAction string
+
+const (
+	NotOkSkip    NotOkAction = "skip"
+	NotOkDefault NotOkAction = "default"
+	NotOkError   NotOkAction = "error"
+)
+
+var okTemplatePattern = regexp.MustCompile(`\.Ok\b`)
+
+func (t ConversionTemplate) validate() error {
+	switch t.OnNotOk {
+	case "", NotOkSkip, NotOkDefault, NotOkError:
+	default:
+		return fmt.Errorf("invalid on_not_ok %q, must be one of %q, %q or %q", t.OnNotOk, NotOkSkip, NotOkDefault, NotOkError)
+	}
+	if err := t.validateUseFunc(); err != nil {
+		return err
+	}
+	if usesOk := okTemplatePattern.MatchString(t.Tmpl); usesOk != (t.OnNotOk != "") {
+		return fmt.Errorf("on_not_ok and {{ .Ok }} must be used together")
+	}
+	if t.Default != "" && t.OnNotOk != NotOkDefault {
+		return fmt.Errorf("default is only used with on_not_ok: %s", NotOkDefault)
+	}
+	if _, err := template.New("default").Parse(t.Default); err != nil {
+		return fmt.Errorf("invalid default template: %w", err)
+	}
+	return nil
 }
 
 func (c *Conversion) GetSourceTypeWithImportsTemplate() TypeWithImportsTemplate {
@@ -79,44 +407,152 @@ func (c *Conversion) GetDestTypeWithImportsTemplate() TypeWithImportsTemplate {
 	return NewTypeWithImportsTemplate(c.DestType, c.Imports)
 }
 
-func (c *Conversion) ExecuteConversionTemplate(sourceVar string, destVar string, errorVar string, importManager *imports.ImportManager) (string, bool) {
-	return c.executeTemplate(c.Conversion.Tmpl, c.Conversion.Error, sourceVar, destVar, errorVar, importManager, "conversion")
+type ConversionTemplateData struct {
+	Source     string
+	Dest       string
+	Error      string
+	FieldName  string
+	SourceType string
+	DestType   string
+	Ctx        string
+	SrcStruct  string
+	Ok         string
+	Args       []string
+}
+
+// TemplateImports returns the imports available to the conversion template, or to the reverse
+// conversion template: the conversion's imports followed by the template's own.
+func (c *Conversion) TemplateImports(isReverse bool) []string {
+	own := c.Conversion.Imports
+	if isReverse {
+		own = c.ReverseConversion.Imports
+	}
+	return append(slices.Clone(c.Imports), own...)
+}
+
+func (c *Conversion) ExecuteConversionTemplate(templateData ConversionTemplateData, importManager *imports.ImportManager) (string, bool) {
+	return c.executeTemplate(c.Conversion.Tmpl, c.Conversion.Error, c.TemplateImports(false), templateData, importManager, "conversion")
 }
 
-func (c *Conversion) ExecuteReverseConversionTemplate(sourceVar string, destVar string, errorVar string, importManager *imports.ImportManager) (string, bool) {
+func (c *Conversion) ExecuteReverseConversionTemplate(templateData ConversionTemplateData, importManager *imports.ImportManager) (string, bool) {
 	if c.ReverseConversion.Tmpl == "" {
-		return fmt.Sprintf("%s = %s", destVar, sourceVar), false
+		return fmt.Sprintf("%s = %s", templateData.Dest, templateData.Source), false
+	}
+	return c.executeTemplate(c.ReverseConversion.Tmpl, c.ReverseConversion.Error, c.TemplateImports(true), templateData, importManager, "reverse_conversion")
+}
+
+func (c *Conversion) executeTemplate(tmplStr string, hasError bool, templateImports []string, templateData ConversionTemplateData, importManager *imports.ImportManager, tmplName string) (string, bool) {
+	rendered := c.render(tmplName, tmplStr, templateImports, templateData, importManager)
+	if c.NilSafe && isNillableType(templateData.SourceType) {
+		return fmt.Sprintf(`if %s != nil {
+		%s
+	}`, templateData.Source, rendered), hasError
+	}
+	return rendered, hasError
+}
+
+// ExecuteDefaultTemplate renders the value assigned when the ok result of the conversion,
+// or of the reverse conversion, is false.
+func (c *Conversion) ExecuteDefaultTemplate(isReverse bool, templateData ConversionTemplateData, importManager *imports.ImportManager) string {
+	if isReverse {
+		return c.render("reverse_conversion default", c.ReverseConversion.Default, c.TemplateImports(true), templateData, importManager)
 	}
-	return c.executeTemplate(c.ReverseConversion.Tmpl, c.ReverseConversion.Error, sourceVar, destVar, errorVar, importManager, "reverse_conversion")
+	return c.render("conversion default", c.Conversion.Default, c.TemplateImports(false), templateData, importManager)
 }
 
-func (c *Conversion) executeTemplate(tmplStr string, hasError bool, sourceVar, destVar, errorVar string, importManager *imports.ImportManager, tmplName string) (string, bool) {
+func (c *Conversion) render(tmplName string, tmplStr string, templateImports []string, templateData ConversionTemplateData, importManager *imports.ImportManager) string {
 	var buf strings.Builder
 	tmpl, err := template.New(tmplName).Parse(tmplStr)
 	if err != nil {
 		panic(err)
 	}
 	data := make(map[string]string)
-	for idx, imp := range c.Imports {
+	for idx, imp := range templateImports {
 		data[fmt.Sprintf("Import%d", idx)] = importManager.GetImportAlias(imp)
 	}
-	data["Source"] = sourceVar
-	data["Dest"] = destVar
-	data["Error"] = errorVar
+	for _, arg := range templateData.Args {
+		data[arg] = arg
+	}
+	data["Source"] = templateData.Source
+	data["Dest"] = templateData.Dest
+	data["Error"] = templateData.Error
+	data["FieldName"] = templateData.FieldName
+	data["SourceType"] = templateData.SourceType
+	data["DestType"] = templateData.DestType
+	if c.NeedsContext {
+		data["Ctx"] = templateData.Ctx
+	}
+	if c.NeedsSrcStruct {
+		data["SrcStruct"] = templateData.SrcStruct
+	}
+	if templateData.Ok != "" {
+		data["Ok"] = templateData.Ok
+	}
+	for name, value := range c.captures {
+		data[name] = value
+	}
 	if err := tmpl.Execute(&buf, data); err != nil {
 		panic(err)
 	}
-	return buf.String(), hasError
+	return buf.String()
 }
 
 type StructDefinition struct {
 	TypeWithImportsTemplate `yaml:",inline"`
 }
 
+// ErrorType replaces the builtin error in the signature of error-returning functions.
+type ErrorType struct {
+	TypeWithImportsTemplate `yaml:",inline"`
+	// Wrap turns an error created by the generated code, {{ .Error }}, into the error type.
+	Wrap string `yaml:"wrap,omitempty"`
+}
+
+// ExecuteWrapTemplate renders Wrap for the given error expression.
+func (e *ErrorType) ExecuteWrapTemplate(errorExpr string, importManager *imports.ImportManager) (string, error) {
+	tmpl, err := template.New("wrap").Parse(e.Wrap)
+	if err != nil {
+		return "", fmt.Errorf("invalid error_type wrap template: %w", err)
+	}
+	data := map[string]string{"Error": errorExpr}
+	for idx, imp := range e.Imports {
+		data[fmt.Sprintf("Import%d", idx)] = importManager.GetImportAlias(imp)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute error_type wrap template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+type FieldKind string
+
+const (
+	FieldKindDefault       FieldKind = ""
+	FieldKindInterface     FieldKind = "interface"
+	FieldKindChan          FieldKind = "chan"
+	FieldKindFunc          FieldKind = "func"
+	FieldKindUnsafePointer FieldKind = "unsafe.Pointer"
+	FieldKindLock          FieldKind = "lock"
+)
+
+func (k FieldKind) Unsupported() bool {
+	switch k {
+	case FieldKindChan, FieldKindFunc, FieldKindUnsafePointer, FieldKindLock:
+		return true
+	}
+	return false
+}
+
 type FieldDefinition struct {
 	Name string
 	Tag  string
+	Kind FieldKind
 	TypeWithImportsTemplate
+	// nilChecks lists the pointers along the path of a nested source field, see resolveSourcePath.
+	nilChecks []string
+	// depth counts the embedded structs the field is promoted through, see shadowPromotedFields.
+	depth int
 }
 
 func NewFieldDefinition(name, typeStr, tag string, importInfos []ImportInfo) FieldDefinition {
@@ -127,7 +563,7 @@ func NewFieldDefinition(name, typeStr, tag string, importInfos []ImportInfo) Fie
 		if importInfo.Alias != nil {
 			old = *importInfo.Alias
 		}
-		typeTemplate = strings.ReplaceAll(typeTemplate, old, fmt.Sprintf("{{ .Import%d }}", idx))
+		typeTemplate = replaceQualifier(typeTemplate, old, fmt.Sprintf("{{ .Import%d }}", idx))
 		imports[idx] = importInfo.Path
 	}
 	return FieldDefinition{
@@ -137,6 +573,30 @@ func NewFieldDefinition(name, typeStr, tag string, importInfos []ImportInfo) Fie
 	}
 }
 
+// replaceQualifier replaces the package qualifier old in typeStr, e.g. "time" in "[]time.Time",
+// skipping identifiers that merely end in it, such as "mytime.Time".
+func replaceQualifier(typeStr, old, replacement string) string {
+	var b strings.Builder
+	for {
+		idx := strings.Index(typeStr, old+".")
+		if idx < 0 {
+			b.WriteString(typeStr)
+			return b.String()
+		}
+		b.WriteString(typeStr[:idx])
+		if idx > 0 && isIdentByte(typeStr[idx-1]) {
+			b.WriteString(old)
+		} else {
+			b.WriteString(replacement)
+		}
+		typeStr = typeStr[idx+len(old):]
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= utf8.RuneSelf
+}
+
 type ImportInfo struct {
 	Alias   *string
 	PkgName string
@@ -190,28 +650,209 @@ func (t TypeWithImportsTemplate) GetUnaliasedType() string {
 	return result
 }
 
-func (t TypeWithImportsTemplate) Equals(other TypeWithImportsTemplate, importManager *imports.ImportManager) bool {
-	renderedT := t.ExecuteTemplate(importManager)
-	renderedOther := other.ExecuteTemplate(importManager)
-	return renderedT == renderedOther
+func (t TypeWithImportsTemplate) GetQualifiedType(pkgName func(importPath string) string) string {
+	return importPlaceholderPattern.ReplaceAllStringFunc(t.TypeTemplate, func(match string) string {
+		idx, _ := strconv.Atoi(importPlaceholderPattern.FindStringSubmatch(match)[1])
+		if idx >= len(t.Imports) {
+			return match
+		}
+		return pkgName(t.Imports[idx])
+	})
+}
+
+// key identifies the type by its full import paths, since the same template
+// can refer to different types depending on the imports. Paths are cleaned, so
+// spellings of the same import path yield the same key.
+func (t TypeWithImportsTemplate) key() string {
+	return t.GetQualifiedType(imports.CleanPath)
+}
+
+func (t TypeWithImportsTemplate) substituteTypeParams(typeParamArgs map[string]TypeWithImportsTemplate) TypeWithImportsTemplate {
+	placeholders := make([]string, 0, len(typeParamArgs))
+	for placeholder := range typeParamArgs {
+		placeholders = append(placeholders, placeholder)
+	}
+	sort.Strings(placeholders)
+
+	result := t
+	for _, placeholder := range placeholders {
+		arg := typeParamArgs[placeholder]
+		if !strings.Contains(result.TypeTemplate, placeholder) {
+			continue
+		}
+		offset := len(result.Imports)
+		argTemplate := importPlaceholderPattern.ReplaceAllStringFunc(arg.TypeTemplate, func(match string) string {
+			idx, _ := strconv.Atoi(importPlaceholderPattern.FindStringSubmatch(match)[1])
+			return fmt.Sprintf("{{ .Import%d }}", idx+offset)
+		})
+		result = NewTypeWithImportsTemplate(
+			regexp.MustCompile(`\b`+placeholder+`\b`).ReplaceAllLiteralString(result.TypeTemplate, argTemplate),
+			append(append([]string{}, result.Imports...), arg.Imports...),
+		)
+	}
+	return result
+}
+
+// SplitTypeArgs parses the type template and returns the import path and name of the
+// named type it refers to, along with its type arguments, e.g. `{{ .Import1 }}.Box[{{ .Import0 }}.Item]`
+// yields the path of Import1, "Box" and `{{ .Import0 }}.Item`. Unqualified names resolve
+// against the first import.
+func (t TypeWithImportsTemplate) SplitTypeArgs() (string, string, []TypeWithImportsTemplate, error) {
+	expression, err := parser.ParseExpr(importPlaceholderPattern.ReplaceAllString(t.TypeTemplate, importIdentPrefix+"$1"))
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to parse type %s: %w", t.TypeTemplate, err)
+	}
+	var indices []ast.Expr
+	switch e := expression.(type) {
+	case *ast.IndexExpr:
+		expression, indices = e.X, []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		expression, indices = e.X, e.Indices
+	}
+	var typeArgs []TypeWithImportsTemplate
+	for _, index := range indices {
+		var buf strings.Builder
+		if err := printer.Fprint(&buf, token.NewFileSet(), index); err != nil {
+			return "", "", nil, fmt.Errorf("failed to print type argument of %s: %w", t.TypeTemplate, err)
+		}
+		typeArgs = append(typeArgs, NewTypeWithImportsTemplate(importIdentPattern.ReplaceAllString(buf.String(), "{{ .Import$1 }}"), t.Imports))
+	}
+	switch e := expression.(type) {
+	case *ast.Ident:
+		pkgPath := ""
+		if len(t.Imports) > 0 {
+			pkgPath = t.Imports[0]
+		}
+		return pkgPath, e.Name, typeArgs, nil
+	case *ast.SelectorExpr:
+		if pkgIdent, ok := e.X.(*ast.Ident); ok {
+			if match := importIdentPattern.FindStringSubmatch(pkgIdent.Name); match != nil {
+				idx, _ := strconv.Atoi(match[1])
+				if idx < len(t.Imports) {
+					return t.Imports[idx], e.Sel.Name, typeArgs, nil
+				}
+			}
+		}
+	}
+	return "", "", nil, fmt.Errorf("type %s is not a named type qualified with an import placeholder", t.TypeTemplate)
+}
+
+func (t TypeWithImportsTemplate) Equals(other TypeWithImportsTemplate) bool {
+	return t.key() == other.key()
 }
 
+var ErrTypeNotFound = errors.New("type not found")
+
+// contextVar names the context.Context parameter injected for conversions that set needs_context.
+const contextVar = "ctx"
+
+// srcStructPattern finds uses of {{ .SrcStruct }} in conversion templates.
+var srcStructPattern = regexp.MustCompile(`\.SrcStruct\b`)
+
+var reservedTemplateKeyPattern = regexp.MustCompile(`^(Source|Dest|Error|FieldName|SourceType|DestType|Ctx|SrcStruct|Ok|(Import|Source)\d+|(Source|Dest)Match\w+)$`)
+
+var importPlaceholderPattern = regexp.MustCompile(`\{\{\s*\.Import(\d+)\s*\}\}`)
+
+// importIdentPrefix stands in for import placeholders while a type template is parsed as Go.
+const importIdentPrefix = "structmapImport"
+
+var importIdentPattern = regexp.MustCompile(`\b` + importIdentPrefix + `(\d+)\b`)
+
 type Generator struct {
 	importManager   *imports.ImportManager
 	packageManager  *packages.PackageManager
 	typeToFieldsMap map[string][]FieldDefinition
 	conversions     Conversions
 	config          Config
+	mappingPath     []string
+	needsContext    bool
+	usedConversions map[int]bool
+	dispatchCases   []dispatchCase
+	testStubs       map[int]string
+	// errorWrapMissing is set when generated code creates an error, but error_type has no wrap.
+	errorWrapMissing bool
+	// tracedConversions holds the conversion lookups already traced, see newConversionTrace.
+	tracedConversions map[string]bool
+	// fieldTags holds the struct tags of the dest and source field being assigned, which
+	// conversions with a field_tag are matched against.
+	fieldTags []string
+	extracted []mappingFields
+	// mappingResults holds the generated mappings by index, mappingFuncs the index of the
+	// mapping generating each function and funcSignatures the signature of every generated
+	// function, see funcSignature.
+	mappingResults map[int]mappingResult
+	mappingFuncs   map[string]int
+	funcSignatures map[string]funcSignature
+	// useFuncErr is set when a use_func can't be resolved while a field is assigned.
+	useFuncErr error
+	// appliesToSameType is set when a shared conversion sets apply_to_same_type, see findConversion.
+	appliesToSameType bool
+	// sameTypeConversions holds the keys of the types shared conversions are declared between
+	// when their source and dest types are the same, see skipsConversionLookup.
+	sameTypeConversions map[string]bool
+	// typePatterns caches the compiled type patterns of conversions, see typePattern.
+	typePatterns map[string]*regexp.Regexp
+}
+
+type mappingResult struct {
+	code   string
+	report MappingReport
+	err    error
+}
+
+// dispatchCase is a mapping the dispatcher can call with src alone.
+type dispatchCase struct {
+	mappingIdx   int
+	fromKey      string
+	fromType     string
+	funcName     string
+	returnsError bool
 }
 
 func NewGenerator(config Config, conversions Conversions) *Generator {
-	return &Generator{
-		importManager:   imports.NewImportManager(),
-		packageManager:  packages.NewPackageManager(),
-		typeToFieldsMap: make(map[string][]FieldDefinition),
-		conversions:     conversions,
-		config:          config,
+	importManager := imports.NewImportManager()
+	importManager.SetAliasPrefix(config.ImportAliasPrefix())
+	config.Mappings = slices.Clone(config.Mappings)
+	for idx := range config.Mappings {
+		config.Mappings[idx].CustomConversions = expandEnumConversions(config.Mappings[idx].CustomConversions)
+		config.Mappings[idx] = config.Mappings[idx].withSourceArgs(config.SrcVar())
+	}
+	g := &Generator{
+		importManager:     importManager,
+		packageManager:    packages.NewPackageManager(),
+		typeToFieldsMap:   make(map[string][]FieldDefinition),
+		usedConversions:   make(map[int]bool),
+		conversions:       Conversions{Conversions: expandEnumConversions(config.AllConversions(conversions))},
+		config:            config,
+		tracedConversions: make(map[string]bool),
+		testStubs:         make(map[int]string),
+		mappingResults:    make(map[int]mappingResult),
+		funcSignatures:    make(map[string]funcSignature),
+		typePatterns:      make(map[string]*regexp.Regexp),
+	}
+	for idx := range g.conversions.Conversions {
+		g.conversions.Conversions[idx].listIdx = idx + 1
+	}
+	g.appliesToSameType = slices.ContainsFunc(g.conversions.Conversions, func(conv Conversion) bool { return conv.ApplyToSameType })
+	g.sameTypeConversions = make(map[string]bool)
+	for _, conv := range g.conversions.Conversions {
+		if source := conv.GetSourceTypeWithImportsTemplate(); !conv.hasTypePattern() && source.Equals(conv.GetDestTypeWithImportsTemplate()) {
+			g.sameTypeConversions[source.key()] = true
+		}
+	}
+	for pkgPath, source := range config.PackageSources {
+		g.packageManager.AddSource(pkgPath, source)
+	}
+	if config.IgnorePackageErrors {
+		g.packageManager.IgnoreErrors(func(err error) {
+			g.warnf("%v", err)
+		})
 	}
+	return g
+}
+
+func (g *Generator) SetPackageLoader(loader packages.Loader) {
+	g.packageManager.SetLoader(loader)
 }
 
 func (g *Generator) AddFields(typeName string, fields []FieldDefinition) {
@@ -224,273 +865,1696 @@ func (g *Generator) GetFields(typeName string) ([]FieldDefinition, bool) {
 }
 
 func (g *Generator) Generate() (string, error) {
-	var funcs []string
+	code, _, err := g.GenerateWithReport()
+	return code, err
+}
 
-	for _, conversion := range g.conversions.Conversions {
-		for _, imp := range conversion.Imports {
-			g.importManager.AddImport(imp)
-		}
+func (g *Generator) GenerateWithReport() (string, Report, error) {
+	funcs, report, err := g.generate()
+	if err != nil {
+		return "", Report{}, err
 	}
+	if g.config.GenerateDispatcher {
+		funcs = append(funcs, g.dispatcher())
+	}
+	code, err := g.renderFile(g.config.OutPackageName, funcs)
+	if err != nil {
+		return "", Report{}, err
+	}
+	report.Imports = g.importManager.Imports()
+	return code, report, nil
+}
 
-	for _, mapping := range g.config.Mappings {
-		for _, customConversion := range mapping.CustomConversions {
-			for _, imp := range customConversion.Imports {
-				g.importManager.AddImport(imp)
-			}
-		}
-
-		for _, additionalArg := range mapping.FuncAdditionalArgs {
-			for _, imp := range additionalArg.Imports {
-				g.importManager.AddImport(imp)
+// GenerateFiles renders the output files, a single OutFile unless SplitFiles is set,
+// in which case every mapping gets its own file named after its function. Mappings
+// with out_file_name or out_package_name set are written to that file and package.
+func (g *Generator) GenerateFiles() ([]File, Report, error) {
+	funcs, report, err := g.generate()
+	if err != nil {
+		return nil, Report{}, err
+	}
+	var names []string
+	fileFuncs := map[string][]string{}
+	filePackages := map[string]string{}
+	fileNames := make([]string, len(g.config.Mappings))
+	for idx, mapping := range g.config.Mappings {
+		name := mapping.OutFileName
+		if name == "" {
+			name = g.config.OutFile()
+			if g.config.SplitFiles {
+				name = strings.ToLower(report.Mappings[idx].FuncName) + ".gen.go"
 			}
 		}
-
-		for _, imp := range mapping.From.Imports {
-			g.importManager.AddImport(imp)
+		packageName := mapping.OutPackageName
+		if packageName == "" {
+			packageName = g.config.OutPackageName
 		}
-		for _, imp := range mapping.To.Imports {
-			g.importManager.AddImport(imp)
+		if existing, ok := filePackages[name]; !ok {
+			names = append(names, name)
+			filePackages[name] = packageName
+		} else if existing != packageName {
+			return nil, Report{}, fmt.Errorf("mappings target the same file %s, but different packages %s and %s", name, existing, packageName)
 		}
-
-		fromPkgPath := ""
-		if len(mapping.From.Imports) > 0 {
-			fromPkgPath = mapping.From.Imports[0]
+		fileFuncs[name] = append(fileFuncs[name], funcs[idx])
+		fileNames[idx] = name
+	}
+	if g.config.GenerateDispatcher {
+		name := g.config.OutFile()
+		if existing, ok := filePackages[name]; !ok {
+			names = append(names, name)
+			filePackages[name] = g.config.OutPackageName
+		} else if existing != g.config.OutPackageName {
+			return nil, Report{}, fmt.Errorf("the dispatcher targets the file %s, but mappings use it with package %s", name, existing)
 		}
-		fromFields, err := g.extractFieldsFromPackage(fromPkgPath, mapping.From.GetUnaliasedType())
+		fileFuncs[name] = append(fileFuncs[name], g.dispatcher())
+	}
+	files := make([]File, 0, len(names))
+	for _, name := range names {
+		code, err := g.renderFile(filePackages[name], fileFuncs[name])
 		if err != nil {
-			return "", fmt.Errorf("failed to extract fields from %s: %w", mapping.From.ExecuteTemplate(g.importManager), err)
+			return nil, Report{}, err
 		}
-		for _, field := range fromFields {
-			for _, imp := range field.Imports {
-				g.importManager.AddImport(imp)
+		files = append(files, File{Name: name, Code: code})
+	}
+	if g.config.GenerateTests {
+		for _, name := range names {
+			var stubs []string
+			for idx := range g.config.Mappings {
+				if fileNames[idx] == name {
+					stubs = append(stubs, g.testStubs[idx])
+				}
+			}
+			if len(stubs) == 0 {
+				continue
+			}
+			code, err := g.renderTestFile(filePackages[name], stubs)
+			if err != nil {
+				return nil, Report{}, err
 			}
+			files = append(files, File{Name: testFileName(name), Code: code, Stub: true})
 		}
+	}
+	report.Imports = g.importManager.Imports()
+	return files, report, nil
+}
 
-		toPkgPath := ""
-		if len(mapping.To.Imports) > 0 {
-			toPkgPath = mapping.To.Imports[0]
+// dispatcher renders Map, which calls the mapper registered for the dynamic type of src.
+// Mappings needing more than src are left out, and so are later mappings from a type
+// that already has one.
+func (g *Generator) dispatcher() string {
+	var cases []string
+	seen := map[string]bool{}
+	// mappings called through use_func may be generated ahead of their turn
+	dispatchCases := slices.Clone(g.dispatchCases)
+	slices.SortStableFunc(dispatchCases, func(a, b dispatchCase) int {
+		return a.mappingIdx - b.mappingIdx
+	})
+	for _, dispatch := range dispatchCases {
+		if seen[dispatch.fromKey] {
+			continue
 		}
-		toFields, err := g.extractFieldsFromPackage(toPkgPath, mapping.To.GetUnaliasedType())
+		seen[dispatch.fromKey] = true
+		call := fmt.Sprintf("%s(v)", dispatch.funcName)
+		if dispatch.returnsError && g.config.ErrorType != nil {
+			// a nil error of a concrete type must not reach the error interface as a non-nil value
+			cases = append(cases, fmt.Sprintf(`case %s:
+		dst, err := %s
 		if err != nil {
-			return "", fmt.Errorf("failed to extract fields to %s: %w", mapping.To.ExecuteTemplate(g.importManager), err)
+			return nil, err
 		}
-		for _, field := range toFields {
-			for _, imp := range field.Imports {
-				g.importManager.AddImport(imp)
-			}
+		return dst, nil`, dispatch.fromType, call))
+			continue
 		}
-
-		g.AddFields(mapping.From.TypeTemplate, fromFields)
-		g.AddFields(mapping.To.TypeTemplate, toFields)
-
-		funcCode, err := g.generateFunction(mapping)
-		if err != nil {
-			return "", fmt.Errorf("failed to generate function: %w", err)
+		if !dispatch.returnsError {
+			call += ", nil"
 		}
-		funcs = append(funcs, funcCode)
+		cases = append(cases, fmt.Sprintf(`case %s:
+		return %s`, dispatch.fromType, call))
 	}
-
-	funcCode := strings.Join(funcs, "\n\n")
-	importCode := g.importManager.RenderImports(funcCode)
-
-	code := fmt.Sprintf(`// Code generated by structmap; DO NOT EDIT.
-package %s
-
-%s
-
-%s
-`, g.config.OutPackageName, importCode, funcCode)
-
-	return code, nil
+	switchExpr := fmt.Sprintf("v := %s.(type)", g.config.SrcVar())
+	if len(cases) == 0 {
+		switchExpr = fmt.Sprintf("%s.(type)", g.config.SrcVar())
+	}
+	fmtAlias := g.importManager.AddStdImport("fmt")
+	cases = append(cases, fmt.Sprintf(`default:
+		return nil, %s.Errorf("structmap: no mapper registered for %%T", %s)`, fmtAlias, g.config.SrcVar()))
+	return fmt.Sprintf(`// Map calls the mapper registered for the dynamic type of %s.
+func Map(%s any) (any, error) {
+	switch %s {
+	%s
+	}
+}`, g.config.SrcVar(), g.config.SrcVar(), switchExpr, strings.Join(cases, "\n\t"))
 }
 
-func (g *Generator) extractFieldsFromPackage(pkgPath string, typeName string) ([]FieldDefinition, error) {
-	structDef, structPkgPath, err := g.findStructDefinition(pkgPath, typeName)
-	if err != nil {
-		return nil, err
-	}
-	var fields []FieldDefinition
-	for _, fld := range structDef.Fields.List {
-		var buf strings.Builder
-		fset := token.NewFileSet()
-		printer.Fprint(&buf, fset, fld.Type)
-		typ := buf.String()
+func (g *Generator) generate() ([]string, Report, error) {
+	var funcs []string
+	var report Report
 
-		importInfos, err := g.findImportSpecsForExpression(fld.Type, structPkgPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to find import specs for expression: %w", err)
-		}
-		tag := ""
-		if fld.Tag != nil {
-			tag = strings.Trim(fld.Tag.Value, "`")
-		}
-		if len(fld.Names) == 0 {
-			embeddedFields, err := g.expandEmbeddedFields(fld, structPkgPath)
+	if err := g.config.validateVarNames(); err != nil {
+		return nil, Report{}, err
+	}
+	if err := g.config.validateLogLevel(); err != nil {
+		return nil, Report{}, err
+	}
+	if err := g.config.validateGoVersion(); err != nil {
+		return nil, Report{}, err
+	}
+	if _, err := template.New("func_name_template").Parse(g.config.FuncNameTemplate); err != nil {
+		return nil, Report{}, fmt.Errorf("invalid func_name_template: %w", err)
+	}
+	if _, err := g.config.OutFileMode(); err != nil {
+		return nil, Report{}, err
+	}
+	if _, err := g.config.OutDirMode(); err != nil {
+		return nil, Report{}, err
+	}
+	if strings.ContainsAny(g.config.GeneratedMarker, "\r\n") {
+		return nil, Report{}, fmt.Errorf("invalid generated_marker %q, must be a single line", g.config.GeneratedMarker)
+	}
+	if marker := g.config.Marker(); !generatedMarkerPattern.MatchString(marker) {
+		g.warnf("generated_marker %q doesn't match %q, tools may not detect the output as generated", marker, generatedMarkerPattern.String())
+	}
+	if g.config.ErrorType != nil {
+		if strings.TrimSpace(g.config.ErrorType.TypeTemplate) == "" {
+			return nil, Report{}, fmt.Errorf("error_type needs a type")
+		}
+		if _, err := template.New("wrap").Parse(g.config.ErrorType.Wrap); err != nil {
+			return nil, Report{}, fmt.Errorf("invalid error_type wrap template: %w", err)
+		}
+		for _, imp := range g.config.ErrorType.Imports {
+			g.importManager.AddImport(imp)
+		}
+	}
+	for _, directive := range g.config.LintDirectives {
+		if strings.ContainsAny(directive, "\r\n") {
+			return nil, Report{}, fmt.Errorf("invalid lint directive %q, must be a single line", directive)
+		}
+	}
+	if err := g.conversions.Validate(); err != nil {
+		return nil, Report{}, err
+	}
+	for _, mapping := range g.config.Mappings {
+		if len(mapping.From) == 0 {
+			return nil, Report{}, fmt.Errorf("mapping to %s has no from type", mapping.To.TypeTemplate)
+		}
+		if err := validateConversionTemplates(mapping.CustomConversions); err != nil {
+			return nil, Report{}, fmt.Errorf("mapping %s → %s: %w", mapping.primary().TypeTemplate, mapping.To.TypeTemplate, err)
+		}
+		if err := validateFieldConversions(mapping.CustomFieldMappings); err != nil {
+			return nil, Report{}, fmt.Errorf("mapping %s → %s: %w", mapping.primary().TypeTemplate, mapping.To.TypeTemplate, err)
+		}
+		if err := validateSourceIndexes(mapping); err != nil {
+			return nil, Report{}, fmt.Errorf("mapping %s → %s: %w", mapping.primary().TypeTemplate, mapping.To.TypeTemplate, err)
+		}
+		if err := g.validateDisabledConversions(mapping); err != nil {
+			return nil, Report{}, fmt.Errorf("mapping %s → %s: %w", mapping.primary().TypeTemplate, mapping.To.TypeTemplate, err)
+		}
+		if err := g.validateFromConcrete(mapping); err != nil {
+			return nil, Report{}, fmt.Errorf("mapping %s: %w", g.mappingKey(mapping), err)
+		}
+		// a type mapped onto itself is usually a wrong type name rather than an intended copy
+		if mapping.primary().Equals(mapping.To.TypeWithImportsTemplate) && !mapping.AllowSelfMap {
+			if g.config.Strict {
+				return nil, Report{}, fmt.Errorf("mapping %s maps a type to itself, set allow_self_map if the copy is intended", g.mappingKey(mapping))
+			}
+			g.warnf("mapping %s maps a type to itself, set allow_self_map if the copy is intended", g.mappingKey(mapping))
+		}
+	}
+
+	mappingFuncs, err := g.mappingFuncNames()
+	if err != nil {
+		return nil, Report{}, err
+	}
+	g.mappingFuncs = mappingFuncs
+	if err := g.validateUseFuncs(); err != nil {
+		return nil, Report{}, err
+	}
+
+	g.extracted = g.extractMappingFields()
+
+	for idx := range g.config.Mappings {
+		if _, done := g.mappingResults[idx]; !done {
+			g.generateMapping(idx)
+		}
+		result := g.mappingResults[idx]
+		if result.err != nil {
+			return nil, Report{}, result.err
+		}
+		funcs = append(funcs, result.code)
+		report.Mappings = append(report.Mappings, result.report)
+	}
+	if g.useFuncErr != nil {
+		return nil, Report{}, g.useFuncErr
+	}
+	if g.errorWrapMissing {
+		return nil, Report{}, fmt.Errorf("error_type %s has no wrap, but the generated code creates errors, e.g. for wrap_conversion_errors, from_concrete, map sources or on_not_ok: error", g.config.ErrorType.TypeTemplate)
+	}
+	for idx, conversion := range g.conversions.Conversions {
+		if !g.usedConversions[idx] {
+			report.UnusedConversions = append(report.UnusedConversions, ConversionReport{SourceType: conversion.SourceType + conversion.SourceTypePattern, DestType: conversion.DestType + conversion.DestTypePattern, FieldName: conversion.FieldName, FieldTag: conversion.FieldTag})
+		}
+	}
+	g.logReport(report)
+
+	return funcs, report, nil
+}
+
+func (g *Generator) renderFile(packageName string, funcs []string) (string, error) {
+	funcCode := strings.Join(funcs, "\n\n")
+	importCode := g.importManager.RenderImports()
+
+	// the generated marker is kept apart from the package clause so it isn't
+	// taken for the package doc, directives like //nolint:all sit right above it
+	var directives strings.Builder
+	for _, directive := range g.config.LintDirectives {
+		directives.WriteString("//" + strings.TrimPrefix(strings.TrimSpace(directive), "//") + "\n")
+	}
+	code := fmt.Sprintf(`%s
+
+%spackage %s
+
+%s
+
+%s
+`, g.config.Marker(), directives.String(), packageName, importCode, funcCode)
+
+	code, err := removeUnusedImports(code)
+	if err != nil {
+		return "", err
+	}
+	code, err = g.explicitReturns(code)
+	if err != nil {
+		return "", err
+	}
+	return g.targetGoVersion(code)
+}
+
+type mappingFields struct {
+	fromFields []FieldDefinition
+	fromErr    error
+	toFields   []FieldDefinition
+	toErr      error
+	argFields  map[string][]FieldDefinition
+	argErr     error
+}
+
+// extractMappingFields loads the from and to fields of every mapping concurrently,
+// results are returned in mapping order so the output doesn't depend on scheduling.
+func (g *Generator) extractMappingFields() []mappingFields {
+	results := make([]mappingFields, len(g.config.Mappings))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(g.config.Mappings)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				mapping := g.config.Mappings[idx]
+				results[idx].fromFields, results[idx].fromErr = g.extractTypeFields(mapping.sourceStruct())
+				results[idx].toFields, results[idx].toErr = g.extractTypeFields(mapping.To.TypeWithImportsTemplate)
+				results[idx].argFields, results[idx].argErr = g.extractArgFields(mapping)
+			}
+		}()
+	}
+	for idx := range g.config.Mappings {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func (g *Generator) extractTypeFields(t TypeWithImportsTemplate) ([]FieldDefinition, error) {
+	if isDynamicMap(t) {
+		return nil, nil
+	}
+	if isInlineStruct(t) {
+		return g.extractInlineStructFields(t)
+	}
+	pkgPath, typeName, typeArgs, err := t.SplitTypeArgs()
+	if err != nil {
+		return nil, err
+	}
+	return g.extractFieldsFromPackage(pkgPath, typeName, typeArgs)
+}
+
+// extractInlineStructFields parses the fields of an anonymous struct type written
+// directly in the config, e.g. `struct { ID string; Owner {{ .Import0 }}.User }`.
+func (g *Generator) extractInlineStructFields(t TypeWithImportsTemplate) ([]FieldDefinition, error) {
+	expression, err := parser.ParseExpr(importPlaceholderPattern.ReplaceAllString(t.TypeTemplate, importIdentPrefix+"$1"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse inline struct %s: %w", t.TypeTemplate, err)
+	}
+	structType, ok := expression.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("inline type %s is not a struct", t.TypeTemplate)
+	}
+	var fields []FieldDefinition
+	for _, fld := range structType.Fields.List {
+		var buf strings.Builder
+		if err := printer.Fprint(&buf, token.NewFileSet(), fld.Type); err != nil {
+			return nil, fmt.Errorf("failed to print field type of inline struct %s: %w", t.TypeTemplate, err)
+		}
+		fieldType := NewTypeWithImportsTemplate(importIdentPattern.ReplaceAllString(buf.String(), "{{ .Import$1 }}"), t.Imports)
+		if len(fld.Names) == 0 {
+			pkgPath, typeName, typeArgs, err := NewTypeWithImportsTemplate(strings.TrimPrefix(fieldType.TypeTemplate, "*"), t.Imports).SplitTypeArgs()
+			if err != nil {
+				return nil, fmt.Errorf("failed to expand embedded field of inline struct: %w", err)
+			}
+			embeddedFields, err := g.extractFieldsFromPackage(pkgPath, typeName, typeArgs)
 			if err != nil {
-				return nil, fmt.Errorf("failed to expand embedded field: %w", err)
+				return nil, fmt.Errorf("failed to expand embedded field of inline struct: %w", err)
+			}
+			for _, embeddedField := range embeddedFields {
+				embeddedField.depth++
+				fields = append(fields, embeddedField)
+			}
+			continue
+		}
+		tag := ""
+		if fld.Tag != nil {
+			tag = strings.Trim(fld.Tag.Value, "`")
+		}
+		kind := g.inlineFieldKind(fld.Type, t.Imports)
+		// names declared together, as in `A, B int`, share the type and tag, but not the imports
+		// slice, which would otherwise alias the mapping's
+		for _, name := range fld.Names {
+			fields = append(fields, FieldDefinition{
+				Name:                    name.Name,
+				Tag:                     tag,
+				Kind:                    kind,
+				TypeWithImportsTemplate: NewTypeWithImportsTemplate(fieldType.TypeTemplate, slices.Clone(fieldType.Imports)),
+			})
+		}
+	}
+	return shadowPromotedFields(fields), nil
+}
+
+// inlineFieldKind is fieldKind for fields of inline structs, whose qualified types refer to
+// the mapping's imports rather than to the imports of a file.
+func (g *Generator) inlineFieldKind(expression ast.Expr, imports []string) FieldKind {
+	if selector, ok := expression.(*ast.SelectorExpr); ok {
+		if pkgIdent, ok := selector.X.(*ast.Ident); ok {
+			if match := importIdentPattern.FindStringSubmatch(pkgIdent.Name); match != nil {
+				idx, _ := strconv.Atoi(match[1])
+				if idx >= len(imports) {
+					return FieldKindDefault
+				}
+				if imports[idx] == "unsafe" && selector.Sel.Name == "Pointer" {
+					return FieldKindUnsafePointer
+				}
+				if imports[idx] == "sync" {
+					switch selector.Sel.Name {
+					case "Mutex", "RWMutex", "WaitGroup", "Once", "Cond":
+						return FieldKindLock
+					}
+				}
+				ts, err := g.findTypeSpec(imports[idx], selector.Sel.Name)
+				if err != nil {
+					return FieldKindDefault
+				}
+				return g.fieldKind(ts.Type, imports[idx])
 			}
-			fields = append(fields, embeddedFields...)
+		}
+	}
+	return g.fieldKind(expression, "")
+}
+
+func removeUnusedImports(code string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", code, parser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated code: %w", err)
+	}
+	for _, spec := range append([]*ast.ImportSpec{}, f.Imports...) {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return "", fmt.Errorf("invalid import path %s: %w", spec.Path.Value, err)
+		}
+		if astutil.UsesImport(f, importPath) {
+			continue
+		}
+		name := ""
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		astutil.DeleteNamedImport(fset, f, name, importPath)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return "", fmt.Errorf("failed to print generated code: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func (g *Generator) extractFieldsFromPackage(pkgPath string, typeName string, typeArgs []TypeWithImportsTemplate) ([]FieldDefinition, error) {
+	return g.extractFields(pkgPath, typeName, typeArgs, nil)
+}
+
+func (g *Generator) extractFields(pkgPath string, typeName string, typeArgs []TypeWithImportsTemplate, embeddingPath []string) ([]FieldDefinition, error) {
+	typeSpec, structPkgPath, err := g.findStructDefinition(pkgPath, typeName)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s.%s", structPkgPath, typeSpec.Name.Name)
+	if slices.Contains(embeddingPath, key) {
+		return nil, fmt.Errorf("circular embedded struct detected: %s", strings.Join(append(embeddingPath, key), " -> "))
+	}
+	embeddingPath = append(slices.Clone(embeddingPath), key)
+	structDef := typeSpec.Type.(*ast.StructType)
+	typeParams := map[string]string{}
+	typeParamArgs := map[string]TypeWithImportsTemplate{}
+	if typeSpec.TypeParams != nil {
+		idx := 0
+		for _, param := range typeSpec.TypeParams.List {
+			for _, name := range param.Names {
+				if idx < len(typeArgs) {
+					placeholder := fmt.Sprintf("structmapTypeParam%d", idx)
+					typeParams[name.Name] = placeholder
+					typeParamArgs[placeholder] = typeArgs[idx]
+				}
+				idx++
+			}
+		}
+		if idx != len(typeArgs) {
+			return nil, fmt.Errorf("type %s expects %d type arguments, got %d", typeName, idx, len(typeArgs))
+		}
+	} else if len(typeArgs) > 0 {
+		return nil, fmt.Errorf("type %s is not generic, got %d type arguments", typeName, len(typeArgs))
+	}
+	structPkg, err := g.packageManager.GetPackage(structPkgPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package %s: %w", structPkgPath, err)
+	}
+	var fields []FieldDefinition
+	for _, fld := range structDef.Fields.List {
+		tag := ""
+		if fld.Tag != nil {
+			tag = strings.Trim(fld.Tag.Value, "`")
+		}
+		names := fld.Names
+		if len(names) == 0 {
+			if g.fieldKind(fld.Type, structPkgPath) == FieldKindInterface {
+				names = []*ast.Ident{ast.NewIdent(embeddedFieldName(fld.Type))}
+			} else {
+				embeddedFields, err := g.expandEmbeddedFields(fld, structPkgPath, structPkg.Name, typeParams, typeParamArgs, embeddingPath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to expand embedded field: %w", err)
+				}
+				for _, embeddedField := range embeddedFields {
+					embeddedField.depth++
+					fields = append(fields, embeddedField)
+				}
+				continue
+			}
+		}
+
+		fieldType, err := g.qualifyDotImports(fld.Type, structPkgPath, typeParams)
+		if err != nil {
+			return nil, err
+		}
+		typeTemplate, err := g.typeTemplate(fieldType, structPkgPath, structPkg.Name, typeParams, typeParamArgs)
+		if err != nil {
+			return nil, err
+		}
+
+		kind := g.fieldKind(fieldType, structPkgPath)
+		// names declared together, as in `X, Y pkg.T`, each get their own definition with the
+		// same type, tag and kind
+		for _, name := range names {
+			fields = append(fields, FieldDefinition{
+				Name:                    name.Name,
+				Tag:                     tag,
+				Kind:                    kind,
+				TypeWithImportsTemplate: NewTypeWithImportsTemplate(typeTemplate.TypeTemplate, slices.Clone(typeTemplate.Imports)),
+			})
+		}
+	}
+	return shadowPromotedFields(fields), nil
+}
+
+// typeTemplate turns a type expression of a struct declared in structPkgPath into a template,
+// with the struct's type parameters replaced by their arguments.
+func (g *Generator) typeTemplate(expression ast.Expr, structPkgPath string, structPkgName string, typeParams map[string]string, typeParamArgs map[string]TypeWithImportsTemplate) (TypeWithImportsTemplate, error) {
+	importInfos, err := g.findImportSpecsForExpression(expression, structPkgPath)
+	if err != nil {
+		return TypeWithImportsTemplate{}, fmt.Errorf("failed to find import specs for expression: %w", err)
+	}
+	typeExpr, qualified := qualifyLocalIdents(expression, structPkgName, typeParams)
+	if qualified {
+		importInfos = append(importInfos, NewImportInfo(nil, structPkgName, structPkgPath))
+	}
+
+	var buf strings.Builder
+	printer.Fprint(&buf, token.NewFileSet(), typeExpr)
+	return NewFieldDefinition("", buf.String(), "", importInfos).substituteTypeParams(typeParamArgs), nil
+}
+
+// shadowPromotedFields applies Go's promotion rules to flattened fields: of the fields sharing a
+// name, the one promoted through the fewest embedded structs wins, so a declared field shadows
+// promoted ones. Names found more than once at that depth are ambiguous in Go and dropped.
+func shadowPromotedFields(fields []FieldDefinition) []FieldDefinition {
+	shallowest := map[string]int{}
+	count := map[string]int{}
+	for _, field := range fields {
+		if depth, ok := shallowest[field.Name]; !ok || field.depth < depth {
+			shallowest[field.Name], count[field.Name] = field.depth, 1
+		} else if field.depth == depth {
+			count[field.Name]++
+		}
+	}
+	visible := make([]FieldDefinition, 0, len(fields))
+	for _, field := range fields {
+		if field.depth == shallowest[field.Name] && count[field.Name] == 1 {
+			visible = append(visible, field)
+		}
+	}
+	return visible
+}
+
+// generateMapping generates the function of the mapping at idx and stores it in mappingResults.
+// Mappings are generated in order, but one called through use_func is generated on first use.
+func (g *Generator) generateMapping(idx int) {
+	mapping := g.config.Mappings[idx]
+	for _, customFieldMapping := range mapping.CustomFieldMappings {
+		for _, imp := range customFieldMapping.Imports {
+			g.importManager.AddImport(imp)
+		}
+	}
+
+	for _, additionalArg := range mapping.FuncAdditionalArgs {
+		for _, imp := range additionalArg.Imports {
+			g.importManager.AddImport(imp)
+		}
+	}
+
+	for _, source := range mapping.From {
+		for _, imp := range source.Imports {
+			g.importManager.AddImport(imp)
+		}
+	}
+	if mapping.FromConcrete != nil {
+		for _, imp := range mapping.FromConcrete.Imports {
+			g.importManager.AddImport(imp)
+		}
+	}
+	for _, imp := range mapping.To.Imports {
+		g.importManager.AddImport(imp)
+	}
+
+	fromFields, err := g.extracted[idx].fromFields, g.extracted[idx].fromErr
+	if err != nil {
+		g.mappingResults[idx] = mappingResult{err: fmt.Errorf("failed to extract fields from %s: %w", mapping.sourceStruct().ExecuteTemplate(g.importManager), err)}
+		return
+	}
+	for _, field := range fromFields {
+		for _, imp := range field.Imports {
+			g.importManager.AddImport(imp)
+		}
+	}
+
+	toFields, err := g.extracted[idx].toFields, g.extracted[idx].toErr
+	if err != nil {
+		g.mappingResults[idx] = mappingResult{err: fmt.Errorf("failed to extract fields to %s: %w", mapping.To.ExecuteTemplate(g.importManager), err)}
+		return
+	}
+	for _, field := range toFields {
+		for _, imp := range field.Imports {
+			g.importManager.AddImport(imp)
+		}
+	}
+
+	if err := g.extracted[idx].argErr; err != nil {
+		g.mappingResults[idx] = mappingResult{err: fmt.Errorf("failed to extract fields of additional args: %w", err)}
+		return
+	}
+	for _, arg := range mapping.FuncAdditionalArgs {
+		if !arg.MatchFields {
+			continue
+		}
+		argFields := g.extracted[idx].argFields[arg.Name]
+		for _, field := range argFields {
+			for _, imp := range field.Imports {
+				g.importManager.AddImport(imp)
+			}
+		}
+		g.AddFields(arg.matchedStruct().key(), argFields)
+	}
+
+	g.AddFields(mapping.sourceStruct().key(), fromFields)
+	g.AddFields(mapping.To.key(), toFields)
+
+	funcCode, mappingReport, err := g.generateFunction(idx, mapping)
+	if err != nil {
+		g.mappingResults[idx] = mappingResult{err: fmt.Errorf("failed to generate function: %w", err)}
+		return
+	}
+	g.mappingResults[idx] = mappingResult{code: funcCode, report: mappingReport}
+}
+
+func (g *Generator) generateFunction(idx int, mapping Mapping) (string, MappingReport, error) {
+	key := g.mappingKey(mapping)
+	if slices.Contains(g.mappingPath, key) {
+		return "", MappingReport{}, fmt.Errorf("circular mapping detected: %s", strings.Join(append(slices.Clone(g.mappingPath), key), " -> "))
+	}
+	g.mappingPath = append(g.mappingPath, key)
+	outerNeedsContext, outerFieldTags := g.needsContext, g.fieldTags
+	g.needsContext = false
+	defer func() {
+		g.mappingPath = g.mappingPath[:len(g.mappingPath)-1]
+		g.needsContext, g.fieldTags = outerNeedsContext, outerFieldTags
+	}()
+
+	sourceFields, ok1 := g.GetFields(mapping.sourceStruct().key())
+	destFields, ok2 := g.GetFields(mapping.To.key())
+	if !ok1 || !ok2 {
+		return "", MappingReport{}, fmt.Errorf("structs not found: %s, %s", mapping.primary().TypeTemplate, mapping.To.TypeTemplate)
+	}
+	if err := validateAdditionalArgs(mapping.FuncAdditionalArgs, []string{g.srcVar(mapping), g.config.DstVar(), g.config.ErrVar(), g.srcParam(mapping)}); err != nil {
+		return "", MappingReport{}, err
+	}
+	if g.config.LogsAt(LogLevelDebug) {
+		sourceFieldsJSON, err := json.MarshalIndent(sourceFields, "", "  ")
+		if err != nil {
+			return "", MappingReport{}, fmt.Errorf("failed to marshal source fields: %w", err)
+		}
+		destFieldsJSON, err := json.MarshalIndent(destFields, "", "  ")
+		if err != nil {
+			return "", MappingReport{}, fmt.Errorf("failed to marshal dest fields: %w", err)
+		}
+		g.logf(LogLevelDebug, "Source fields:\n%s", string(sourceFieldsJSON))
+		g.logf(LogLevelDebug, "Dest fields:\n%s", string(destFieldsJSON))
+	}
+	byName := map[string]FieldDefinition{}
+	tags := mapping.MatchTags()
+	byTag := map[string]map[string]FieldDefinition{}
+	for _, tag := range tags {
+		byTag[tag] = map[string]FieldDefinition{}
+	}
+	for _, sourceField := range sourceFields {
+		byName[sourceField.Name] = sourceField
+		for _, tag := range tags {
+			if tv := tagValue(sourceField.Tag, tag); tv != "" {
+				byTag[tag][tv] = sourceField
+			}
+		}
+	}
+
+	if err := g.resolveSourcePaths(mapping, byName); err != nil {
+		return "", MappingReport{}, err
+	}
+	if err := validateSplitMappings(mapping.CustomFieldMappings, byName, destFields, mapping); err != nil {
+		return "", MappingReport{}, err
+	}
+
+	fromTypeTemplate := mapping.primary().TypeWithImportsTemplate
+	toTypeTemplate := mapping.To.TypeWithImportsTemplate
+
+	funcName := mapping.FuncName
+	if funcName == "" {
+		var err error
+		funcName, err = g.funcName(fromTypeTemplate, toTypeTemplate)
+		if err != nil {
+			return "", MappingReport{}, err
+		}
+	}
+	usedTypes := []TypeWithImportsTemplate{fromTypeTemplate, toTypeTemplate}
+	for _, field := range append(slices.Clone(sourceFields), destFields...) {
+		usedTypes = append(usedTypes, field.TypeWithImportsTemplate)
+	}
+	if err := g.checkGenerics(funcName, usedTypes...); err != nil {
+		return "", MappingReport{}, err
+	}
+
+	report := MappingReport{
+		FuncName: funcName,
+		From:     fromTypeTemplate.GetUnaliasedType(),
+		To:       toTypeTemplate.GetUnaliasedType(),
+		FromType: fromTypeTemplate.key(),
+		ToType:   toTypeTemplate.key(),
+	}
+	var assigns []string
+	var hasError bool
+	var err error
+	switch {
+	case isDynamicMap(toTypeTemplate) && mapping.InPlace:
+		err = fmt.Errorf("mapping %s sets in_place, which is not supported for map destinations", funcName)
+	case isDynamicMap(toTypeTemplate):
+		assigns, err = g.structToMapAssignments(mapping, sourceFields, tags, &report)
+	case isDynamicMap(fromTypeTemplate):
+		assigns, hasError, err = g.mapToStructAssignments(mapping, destFields, tags, &report)
+	default:
+		assigns, hasError, err = g.fieldAssignments(mapping, sourceFields, destFields, byName, byTag, tags, &report)
+		if err == nil && mapping.DocumentDropped {
+			assigns = append(assigns, g.droppedSourceFields(mapping, sourceFields, report)...)
+		}
+	}
+	if err != nil {
+		return "", MappingReport{}, err
+	}
+	if mapping.FromConcrete != nil {
+		if mapping.AlwaysError != nil && !*mapping.AlwaysError {
+			return "", MappingReport{}, fmt.Errorf("mapping %s sets always_error: false, but from_concrete needs an error for failed type assertions", funcName)
+		}
+		assigns = append([]string{g.concreteAssertion(mapping)}, assigns...)
+		hasError = true
+	}
+
+	funcArgs := g.functionParameters(mapping, fromTypeTemplate)
+	if g.needsContext && !slices.Contains(mapping.AdditionalArgNames(), contextVar) {
+		if slices.Contains([]string{g.config.SrcVar(), g.config.DstVar(), g.config.ErrVar()}, contextVar) {
+			return "", MappingReport{}, fmt.Errorf("mapping %s uses conversions that need a context, but %q is already taken by src_name, dst_name or err_name", funcName, contextVar)
+		}
+		contextAlias := g.importManager.AddStdImport("context")
+		funcArgs = slices.Insert(funcArgs, 0, fmt.Sprintf("%s %s.Context", contextVar, contextAlias))
+	}
+
+	if mapping.AlwaysError != nil {
+		if !*mapping.AlwaysError && hasError {
+			return "", MappingReport{}, fmt.Errorf("mapping %s sets always_error: false, but uses conversions that return an error", funcName)
+		}
+		hasError = *mapping.AlwaysError
+	}
+
+	if len(funcArgs) == 1 && !mapping.InPlace && (mapping.OutPackageName == "" || mapping.OutPackageName == g.config.OutPackageName) {
+		g.dispatchCases = append(g.dispatchCases, dispatchCase{
+			mappingIdx:   idx,
+			fromKey:      fromTypeTemplate.key(),
+			fromType:     fromTypeTemplate.ExecuteTemplate(g.importManager),
+			funcName:     funcName,
+			returnsError: hasError,
+		})
+	}
+
+	var results []string
+	if !mapping.InPlace {
+		results = append(results, fmt.Sprintf("%s %s", g.config.DstVar(), toTypeTemplate.ExecuteTemplate(g.importManager)))
+	}
+	if hasError {
+		results = append(results, fmt.Sprintf("%s %s", g.config.ErrVar(), g.errorTypeName()))
+	}
+	resultList := ""
+	if len(results) > 0 {
+		resultList = fmt.Sprintf(" (%s)", strings.Join(results, ", "))
+	}
+	if g.config.GenerateTests {
+		g.testStubs[idx] = g.testStub(mapping, funcName, funcArgs, hasError)
+	}
+	g.funcSignatures[funcName] = funcSignature{returnsError: hasError, needsContext: g.needsContext}
+	return fmt.Sprintf(`// %s copies %s → %s
+func %s(%s)%s {
+    %s
+    return
+}`, funcName, fromTypeTemplate.GetQualifiedType(g.packageName), toTypeTemplate.GetQualifiedType(g.packageName), funcName, strings.Join(funcArgs, ", "), resultList, strings.Join(assigns, "\n\t")), report, nil
+}
+
+// droppedSourceFields lists the source fields no dest field is mapped from as blank assignments,
+// so dropping a field shows up in diffs and renaming it breaks stale generated code.
+func (g *Generator) droppedSourceFields(mapping Mapping, sourceFields []FieldDefinition, report MappingReport) []string {
+	used := map[string]bool{}
+	for _, field := range report.Fields {
+		switch field.MatchedBy {
+		case MatchKindUnmapped, MatchKindSkipped, MatchKindAdditionalArg:
+			continue
+		}
+		for _, name := range strings.Split(field.Source, ", ") {
+			// a nested source path such as Address.City uses the top-level field Address
+			name, _, _ = strings.Cut(name, ".")
+			used[name] = true
+		}
+	}
+	var lines []string
+	for _, field := range sourceFields {
+		if !used[field.Name] {
+			lines = append(lines, fmt.Sprintf("_ = %s.%s", g.srcVar(mapping), field.Name))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return append([]string{"// source fields not mapped to any dest field"}, lines...)
+}
+
+// srcParam names the src parameter, which is only asserted into src when From is an interface.
+func (g *Generator) srcParam(mapping Mapping) string {
+	if mapping.FromConcrete != nil {
+		return g.srcVar(mapping) + "Value"
+	}
+	return g.srcVar(mapping)
+}
+
+// validateFromConcrete checks that from_concrete implements the from interface, so the type
+// assertion compiles. It's skipped when either type can't be type checked, e.g. generic types or
+// packages parsed from source, leaving mistakes to the compiler.
+func (g *Generator) validateFromConcrete(mapping Mapping) error {
+	if mapping.FromConcrete == nil {
+		return nil
+	}
+	fromPath, fromName, fromArgs, err := mapping.primary().SplitTypeArgs()
+	if err != nil || len(fromArgs) > 0 {
+		return nil
+	}
+	concretePath, concreteName, concreteArgs, err := mapping.sourceStruct().SplitTypeArgs()
+	if err != nil || len(concreteArgs) > 0 {
+		return nil
+	}
+	from, concrete := g.packageManager.LookupType(fromPath, fromName), g.packageManager.LookupType(concretePath, concreteName)
+	if from == nil || concrete == nil {
+		return nil
+	}
+	iface, ok := from.Type().Underlying().(*types.Interface)
+	if !ok {
+		return fmt.Errorf("from_concrete needs from to be an interface, %s isn't one", mapping.primary().GetQualifiedType(g.packageName))
+	}
+	concreteType := concrete.Type()
+	if strings.HasPrefix(strings.TrimSpace(mapping.FromConcrete.TypeTemplate), "*") {
+		concreteType = types.NewPointer(concreteType)
+	}
+	if !types.Implements(concreteType, iface) {
+		return fmt.Errorf("from_concrete %s doesn't implement %s", mapping.FromConcrete.GetQualifiedType(g.packageName), mapping.primary().GetQualifiedType(g.packageName))
+	}
+	return nil
+}
+
+func (g *Generator) concreteAssertion(mapping Mapping) string {
+	concrete := mapping.FromConcrete.ExecuteTemplate(g.importManager)
+	fmtAlias := g.importManager.AddStdImport("fmt")
+	errorValue := g.errorValue(fmt.Sprintf("%s.Errorf(\"expected %s, got %%T\", %s)", fmtAlias, mapping.FromConcrete.GetQualifiedType(g.packageName), g.srcParam(mapping)))
+	return fmt.Sprintf(`%s, ok := %s.(%s)
+	if !ok {
+		%s = %s
+		return
+	}`, g.srcVar(mapping), g.srcParam(mapping), concrete, g.config.ErrVar(), errorValue)
+}
+
+// functionParameters renders src, dst for in-place mappings and the additional args, then moves
+// every arg with a position to that index of the parameter list. Positioned
+// args are placed in ascending position order, ties in declaration order.
+func (g *Generator) functionParameters(mapping Mapping, fromTypeTemplate TypeWithImportsTemplate) []string {
+	params := []string{fmt.Sprintf("%s %s", g.srcParam(mapping), fromTypeTemplate.ExecuteTemplate(g.importManager))}
+	for _, arg := range mapping.sourceArgs() {
+		params = append(params, arg.RenderParameter(g.importManager))
+	}
+	if mapping.InPlace {
+		params = append(params, fmt.Sprintf("%s *%s", g.config.DstVar(), mapping.To.ExecuteTemplate(g.importManager)))
+	}
+	var positioned []AdditionalArg
+	for _, arg := range mapping.FuncAdditionalArgs {
+		if arg.source {
 			continue
 		}
-		for _, name := range fld.Names {
-			fields = append(fields, NewFieldDefinition(name.Name, typ, tag, importInfos))
+		if arg.Position != nil {
+			positioned = append(positioned, arg)
+			continue
 		}
+		params = append(params, arg.RenderParameter(g.importManager))
+	}
+	sort.SliceStable(positioned, func(i, j int) bool {
+		return *positioned[i].Position < *positioned[j].Position
+	})
+	for _, arg := range positioned {
+		idx := min(max(*arg.Position, 0), len(params))
+		params = slices.Insert(params, idx, arg.RenderParameter(g.importManager))
 	}
-	return fields, nil
+	return params
 }
 
-func (g *Generator) generateFunction(mapping Mapping) (string, error) {
-	sourceFields, ok1 := g.GetFields(mapping.From.TypeTemplate)
-	destFields, ok2 := g.GetFields(mapping.To.TypeTemplate)
-	if !ok1 || !ok2 {
-		return "", fmt.Errorf("structs not found: %s, %s", mapping.From.TypeTemplate, mapping.To.TypeTemplate)
+func (g *Generator) fieldAssignments(
+	mapping Mapping,
+	sourceFields []FieldDefinition,
+	destFields []FieldDefinition,
+	byName map[string]FieldDefinition,
+	byTag map[string]map[string]FieldDefinition,
+	tags []string,
+	report *MappingReport,
+) ([]string, bool, error) {
+	if mapping.MatchByPosition && len(sourceFields) != len(destFields) {
+		return nil, false, fmt.Errorf("match_by_position needs the same number of fields, %s has %d and %s has %d", mapping.sourceStruct().GetQualifiedType(g.packageName), len(sourceFields), mapping.To.GetQualifiedType(g.packageName), len(destFields))
 	}
-	if g.config.Debug {
-		sourceFieldsJSON, err := json.MarshalIndent(sourceFields, "", "  ")
-		if err != nil {
-			return "", fmt.Errorf("failed to marshal source fields: %w", err)
+	var assigns []string
+	hasError := false
+	defer func() {
+		g.fieldTags = nil
+	}()
+	for position, destField := range destFields {
+		fieldReport := FieldReport{DestField: destField.Name, MatchedBy: MatchKindUnmapped}
+		if mapping.RespectSkipTag && hasSkipTag(destField.Tag, tags) {
+			fieldReport.MatchedBy = MatchKindSkipped
+			report.Fields = append(report.Fields, fieldReport)
+			continue
 		}
-		destFieldsJSON, err := json.MarshalIndent(destFields, "", "  ")
+		if composite := findCompositeMapping(mapping.CustomFieldMappings, destField); composite != nil {
+			assignment, err := g.compositeAssignment(mapping, *composite, destField, byName)
+			if err != nil {
+				return nil, false, err
+			}
+			var compositeSources []FieldDefinition
+			for _, name := range composite.SourceFields {
+				compositeSources = append(compositeSources, byName[name])
+			}
+			assigns = append(assigns, g.guardSourcePaths(mapping, assignment, compositeSources...))
+			fieldReport.Source = strings.Join(composite.SourceFields, ", ")
+			fieldReport.MatchedBy = MatchKindComposite
+			report.Fields = append(report.Fields, fieldReport)
+			continue
+		}
+		if split, destIdx := findSplitMapping(mapping.CustomFieldMappings, destField); split != nil {
+			assignment, err := split.ExecuteSplitTemplate(g.srcVar(mapping)+"."+split.SourceField, g.config.DstVar()+"."+destField.Name, destIdx, mapping.AdditionalArgNames(), g.importManager)
+			if err != nil {
+				return nil, false, fmt.Errorf("custom field mapping for %s: %w", destField.Name, err)
+			}
+			assigns = append(assigns, g.guardSourcePaths(mapping, assignment, byName[split.SourceField]))
+			fieldReport.Source = split.SourceField
+			fieldReport.MatchedBy = MatchKindSplit
+			report.Fields = append(report.Fields, fieldReport)
+			continue
+		}
+		sourceField, matchedBy, customFieldMapping := findSourceForDest(destField, byName, byTag, mapping.CustomFieldMappings, tags, sourceFields, g.config.FieldMatchers)
+		additionalArg := findAdditionalArg(mapping.FuncAdditionalArgs, destField)
+		var sourceMapping *CustomFieldMapping
+		if additionalArg == nil {
+			var err error
+			additionalArg, sourceMapping, err = g.findSourcesField(mapping, destField, sourceField, matchedBy, tags)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		g.fieldTags = []string{destField.Tag}
+		if sourceField != nil && additionalArg == nil {
+			g.fieldTags = append(g.fieldTags, sourceField.Tag)
+		}
+		if sourceField == nil && additionalArg == nil {
+			additionalArg = g.findArgField(mapping, destField, tags)
+		}
+		if sourceField == nil && additionalArg == nil && mapping.MatchByPosition {
+			sourceField, matchedBy = &sourceFields[position], MatchKindPosition
+			g.fieldTags = append(g.fieldTags, sourceField.Tag)
+			if !g.positionallyAssignable(*sourceField, destField, mapping) {
+				return nil, false, fmt.Errorf("match_by_position pairs %s %s with %s %s, add a conversion for this type pair", sourceField.Name, sourceField.ExecuteTemplate(g.importManager), destField.Name, destField.ExecuteTemplate(g.importManager))
+			}
+		}
+		if sourceMapping != nil {
+			fieldReport.Source = additionalArg.Name
+			fieldReport.MatchedBy = MatchKindCustom
+		} else if additionalArg != nil {
+			fieldReport.Source = additionalArg.Name
+			fieldReport.MatchedBy = MatchKindAdditionalArg
+		} else if sourceField != nil {
+			fieldReport.Source = sourceField.Name
+			fieldReport.MatchedBy = matchedBy
+		}
+		defaultOnNil := customFieldMapping != nil && customFieldMapping.DefaultOnNil != "" && additionalArg == nil && fieldReport.MatchedBy == MatchKindCustom
+		var fieldConversion *Conversion
+		if customFieldMapping != nil && customFieldMapping.Conversion != nil && additionalArg == nil && fieldReport.MatchedBy == MatchKindCustom {
+			fieldConversion = customFieldMapping.fieldConversion(sourceField.TypeWithImportsTemplate, destField.TypeWithImportsTemplate)
+		}
+		if sourceMapping != nil && sourceMapping.Conversion != nil {
+			fieldConversion = sourceMapping.fieldConversion(additionalArg.TypeWithImportsTemplate, destField.TypeWithImportsTemplate)
+		}
+		if additionalArg != nil && fieldConversion == nil {
+			if err := g.checkAssignable(mapping, additionalArg.Name, additionalArg.TypeWithImportsTemplate, destField); err != nil {
+				return nil, false, err
+			}
+		} else if sourceField != nil && fieldConversion == nil && !defaultOnNil && sourceField.Kind != FieldKindInterface && !sourceField.Kind.Unsupported() {
+			if err := g.checkAssignable(mapping, sourceField.Name, sourceField.TypeWithImportsTemplate, destField); err != nil {
+				return nil, false, err
+			}
+		}
+		assignment, returnsError, err := g.assignmentLine(mapping, sourceField, destField, additionalArg, fieldConversion, &fieldReport)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal dest fields: %w", err)
+			return nil, false, err
+		}
+		if defaultOnNil {
+			assignment, returnsError, err = g.defaultOnNilAssignment(mapping, *customFieldMapping, *sourceField, destField, assignment, returnsError)
+			if err != nil {
+				return nil, false, err
+			}
 		}
-		log.Printf("Source fields:\n%s", string(sourceFieldsJSON))
-		log.Printf("Dest fields:\n%s", string(destFieldsJSON))
+		omitEmpty := customFieldMapping != nil && customFieldMapping.OmitEmpty && additionalArg == nil && fieldReport.MatchedBy == MatchKindCustom
+		zeroToNil := mapping.ZeroToNil && sourceField != nil && pointerTo(sourceField.TypeWithImportsTemplate, destField.TypeWithImportsTemplate)
+		if mapping.RespectOmitEmpty && sourceField != nil && additionalArg == nil && !defaultOnNil && !zeroToNil && assignment != "" && fieldReport.MatchedBy != MatchKindSkipped && tagOmitsEmpty(destField.Tag, tags) {
+			omitEmpty = true
+		}
+		if omitEmpty {
+			assignment = fmt.Sprintf(`if %s {
+		%s
+	}`, g.nonZeroCondition(g.srcVar(mapping)+"."+sourceField.Name, sourceField.TypeWithImportsTemplate), assignment)
+		}
+		if sourceField != nil && additionalArg == nil {
+			assignment = g.guardSourcePaths(mapping, assignment, *sourceField)
+		}
+		if assignment != "" {
+			assigns = append(assigns, assignment)
+		}
+		if returnsError {
+			hasError = true
+		}
+		report.Fields = append(report.Fields, fieldReport)
 	}
-	byName := map[string]FieldDefinition{}
-	tag := mapping.Tag
-	if tag == "" {
-		tag = "json"
+	return assigns, hasError, nil
+}
+
+// defaultOnNilAssignment guards the assignment of a nillable source with a nil check and assigns
+// the configured default otherwise. Without a conversion, a *T source is dereferenced into a T dest.
+func (g *Generator) defaultOnNilAssignment(
+	mapping Mapping,
+	customFieldMapping CustomFieldMapping,
+	source FieldDefinition,
+	dest FieldDefinition,
+	assignment string,
+	returnsError bool,
+) (string, bool, error) {
+	if customFieldMapping.OmitEmpty {
+		return "", false, fmt.Errorf("custom field mapping for %s: default_on_nil and omit_empty can't be combined", dest.Name)
+	}
+	sourceExpr := g.srcVar(mapping) + "." + source.Name
+	destExpr := g.config.DstVar() + "." + dest.Name
+	if !isNillableType(source.ExecuteTemplate(g.importManager)) {
+		return "", false, fmt.Errorf("custom field mapping for %s: default_on_nil needs a pointer, slice or map source, %s is %s", dest.Name, source.Name, source.ExecuteTemplate(g.importManager))
+	}
+	if conversion, _ := g.findConversion(source.TypeWithImportsTemplate, dest.TypeWithImportsTemplate, dest.Name, mapping); conversion == nil && customFieldMapping.Conversion == nil {
+		elem := NewTypeWithImportsTemplate(strings.TrimPrefix(source.TypeTemplate, "*"), source.Imports)
+		if strings.HasPrefix(source.TypeTemplate, "*") && elem.Equals(dest.TypeWithImportsTemplate) {
+			assignment = fmt.Sprintf("%s = *%s", destExpr, sourceExpr)
+		}
+	}
+	defaultValue, err := customFieldMapping.ExecuteDefaultTemplate(mapping.AdditionalArgNames(), g.importManager)
+	if err != nil {
+		return "", false, fmt.Errorf("custom field mapping for %s: %w", dest.Name, err)
 	}
-	byTag := map[string]FieldDefinition{}
+	return fmt.Sprintf(`if %s != nil {
+		%s
+	} else {
+		%s = %s
+	}`, sourceExpr, assignment, destExpr, defaultValue), returnsError, nil
+}
+
+// structToMapAssignments fills a map[string]any dest keyed by each source field's
+// match tag, falling back to the field name.
+func (g *Generator) structToMapAssignments(mapping Mapping, sourceFields []FieldDefinition, tags []string, report *MappingReport) ([]string, error) {
+	assigns := []string{fmt.Sprintf("%s = make(%s, %d)", g.config.DstVar(), mapping.To.ExecuteTemplate(g.importManager), len(sourceFields))}
 	for _, sourceField := range sourceFields {
-		byName[sourceField.Name] = sourceField
-		if tv := tagValue(sourceField.Tag, tag); tv != "" {
-			byTag[tv] = sourceField
+		if mapping.RespectSkipTag && hasSkipTag(sourceField.Tag, tags) {
+			report.Fields = append(report.Fields, FieldReport{DestField: sourceField.Name, MatchedBy: MatchKindSkipped})
+			continue
 		}
+		key, matchedBy := dynamicMapKey(sourceField, tags)
+		assigns = append(assigns, fmt.Sprintf("%s[%q] = %s.%s", g.config.DstVar(), key, g.srcVar(mapping), sourceField.Name))
+		report.Fields = append(report.Fields, FieldReport{DestField: key, Source: sourceField.Name, MatchedBy: matchedBy})
 	}
+	return assigns, nil
+}
 
+// mapToStructAssignments reads every dest field from a map[string]any source with a
+// type assertion, a value of the wrong type fails the mapping unless always_error is false.
+func (g *Generator) mapToStructAssignments(mapping Mapping, destFields []FieldDefinition, tags []string, report *MappingReport) ([]string, bool, error) {
+	checked := mapping.AlwaysError == nil || *mapping.AlwaysError
 	var assigns []string
-	hasError := false
 	for _, destField := range destFields {
-		sourceField := findSourceForDest(destField, byName, byTag, mapping.CustomFieldMappings, tag, sourceFields)
-		additionalArg := findAdditionalArg(mapping.FuncAdditionalArgs, destField)
-		assignment, returnsError := g.assignmentLine(sourceField, destField, g.conversions.Conversions, mapping.CustomConversions, additionalArg)
-		if assignment != "" {
-			assigns = append(assigns, assignment)
+		if mapping.RespectSkipTag && hasSkipTag(destField.Tag, tags) {
+			report.Fields = append(report.Fields, FieldReport{DestField: destField.Name, MatchedBy: MatchKindSkipped})
+			continue
 		}
-		if returnsError {
-			hasError = true
+		key, matchedBy := dynamicMapKey(destField, tags)
+		destExpr := g.config.DstVar() + "." + destField.Name
+		destType := destField.ExecuteTemplate(g.importManager)
+		if !checked {
+			assigns = append(assigns, fmt.Sprintf("%s, _ = %s[%q].(%s)", destExpr, g.srcVar(mapping), key, destType))
+		} else {
+			fmtAlias := g.importManager.AddStdImport("fmt")
+			errorValue := g.errorValue(fmt.Sprintf("%s.Errorf(\"field %%q: expected %%T, got %%T\", %q, %s, v)", fmtAlias, key, destExpr))
+			assigns = append(assigns, fmt.Sprintf(`if v, ok := %s[%q]; ok {
+		if %s, ok = v.(%s); !ok {
+			%s = %s
+			return
+		}
+	}`, g.srcVar(mapping), key, destExpr, destType, g.config.ErrVar(), errorValue))
 		}
+		report.Fields = append(report.Fields, FieldReport{DestField: destField.Name, Source: key, MatchedBy: matchedBy})
 	}
+	return assigns, checked, nil
+}
 
-	fromTypeTemplate := mapping.From.TypeWithImportsTemplate
-	toTypeTemplate := mapping.To.TypeWithImportsTemplate
-
-	funcName := mapping.FuncName
-	if funcName == "" {
-		funcName = g.funcName(fromTypeTemplate, toTypeTemplate)
+func dynamicMapKey(field FieldDefinition, tags []string) (string, MatchKind) {
+	for _, tag := range tags {
+		if tv := tagValue(field.Tag, tag); tv != "" {
+			return tv, MatchKindTag
+		}
 	}
+	return field.Name, MatchKindName
+}
 
-	funcArgs := []string{fmt.Sprintf("src %s", fromTypeTemplate.ExecuteTemplate(g.importManager))}
-	for _, arg := range mapping.FuncAdditionalArgs {
-		funcArgs = append(funcArgs, arg.RenderParameter(g.importManager))
+func isInlineStruct(t TypeWithImportsTemplate) bool {
+	return strings.HasPrefix(strings.TrimSpace(t.TypeTemplate), "struct")
+}
+
+func isDynamicMap(t TypeWithImportsTemplate) bool {
+	typ := strings.ReplaceAll(t.TypeTemplate, " ", "")
+	return typ == "map[string]any" || typ == "map[string]interface{}"
+}
+
+func (g *Generator) packageName(importPath string) string {
+	if pkg, err := g.packageManager.GetPackage(importPath); err == nil && pkg != nil && pkg.Name != "" {
+		return pkg.Name
 	}
+	return path.Base(importPath)
+}
 
-	retType := toTypeTemplate.ExecuteTemplate(g.importManager)
-	if hasError {
-		return fmt.Sprintf(`// %s copies %s → %s
-func %s(%s) (dst %s, err error) {
-    %s
-    return
-}`, funcName, fromTypeTemplate.GetUnaliasedType(), toTypeTemplate.GetUnaliasedType(), funcName, strings.Join(funcArgs, ", "), retType, strings.Join(assigns, "\n\t")), nil
-	} else {
-		return fmt.Sprintf(`// %s copies %s → %s
-func %s(%s) (dst %s) {
-    %s
-    return
-}`, funcName, fromTypeTemplate.GetUnaliasedType(), toTypeTemplate.GetUnaliasedType(), funcName, strings.Join(funcArgs, ", "), retType, strings.Join(assigns, "\n\t")), nil
+// defaultFuncNameTemplate names mappings without func_name unless func_name_template is set.
+const defaultFuncNameTemplate = "Map{{ .From }}To{{ .To }}"
+
+func (g *Generator) funcName(fromType TypeWithImportsTemplate, toType TypeWithImportsTemplate) (string, error) {
+	funcNameTemplate := g.config.FuncNameTemplate
+	if funcNameTemplate == "" {
+		funcNameTemplate = defaultFuncNameTemplate
+	}
+	tmpl, err := template.New("func_name_template").Parse(funcNameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid func_name_template: %w", err)
 	}
+	var buf strings.Builder
+	data := map[string]string{"From": funcNamePart(fromType), "To": funcNamePart(toType)}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute func_name_template: %w", err)
+	}
+	name := buf.String()
+	if !token.IsIdentifier(name) {
+		return "", fmt.Errorf("func_name_template renders %q for %s → %s, which is not a valid Go identifier", name, fromType.GetUnaliasedType(), toType.GetUnaliasedType())
+	}
+	return name, nil
 }
 
-func (g *Generator) funcName(fromType TypeWithImportsTemplate, toType TypeWithImportsTemplate) string {
-	return fmt.Sprintf("Map%sTo%s", fromType.GetUnaliasedType(), toType.GetUnaliasedType())
+func funcNamePart(t TypeWithImportsTemplate) string {
+	if isDynamicMap(t) {
+		return "Map"
+	}
+	if isInlineStruct(t) {
+		return "Struct"
+	}
+	return typeIdentifier(t.GetUnaliasedType())
 }
 
 func (g *Generator) assignmentLine(
+	mapping Mapping,
 	source *FieldDefinition,
 	dest FieldDefinition,
-	conversions []Conversion,
-	customConversions []Conversion,
 	additionalArg *AdditionalArg,
-) (string, bool) {
+	fieldConversion *Conversion,
+	fieldReport *FieldReport,
+) (string, bool, error) {
+	var sourceExpr string
+	var sourceType TypeWithImportsTemplate
 	if additionalArg != nil {
-		conversion, isReverse := g.findConversion(additionalArg.TypeWithImportsTemplate, dest.TypeWithImportsTemplate, conversions, customConversions)
-		return g.assignmentWithConversion(
-			additionalArg.Name,
-			dest,
-			conversion,
-			isReverse,
-		)
+		sourceExpr = additionalArg.Name
+		sourceType = additionalArg.TypeWithImportsTemplate
 	} else if source != nil {
-		conversion, isReverse := g.findConversion(source.TypeWithImportsTemplate, dest.TypeWithImportsTemplate, conversions, customConversions)
-
-		return g.assignmentWithConversion(
-			"src."+source.Name,
-			dest,
-			conversion,
-			isReverse,
-		)
+		sourceExpr = g.srcVar(mapping) + "." + source.Name
+		sourceType = source.TypeWithImportsTemplate
+	} else if mapping.ExplicitDefaults {
+		return fmt.Sprintf("%s.%s = %s // default", g.config.DstVar(), dest.Name, g.zeroValue(dest.TypeWithImportsTemplate)), false, nil
 	} else {
-		return "// no matching source found for field: " + dest.Name + ", consider adding an additional arg or aligning the fields", false
+		return "// no matching source found for field: " + dest.Name + ", consider adding an additional arg or aligning the fields", false, nil
+	}
+
+	destExpr := g.config.DstVar() + "." + dest.Name
+	if fieldConversion != nil {
+		fieldReport.Conversion = "custom_field_mappings " + g.describeConversion(fieldConversion, false)
+		assignment, hasError := g.assignmentWithConversion(mapping, sourceExpr, sourceType, destExpr, dest, fieldConversion, false)
+		return assignment, hasError, nil
+	}
+	conversion, isReverse := g.findConversion(sourceType, dest.TypeWithImportsTemplate, dest.Name, mapping)
+	if conversion == nil && !sourceType.Equals(dest.TypeWithImportsTemplate) {
+		if sourceLen, sourceElem, ok := arrayType(sourceType); ok {
+			if destLen, destElem, ok := arrayType(dest.TypeWithImportsTemplate); ok {
+				return g.arrayAssignment(mapping, sourceExpr, sourceLen, sourceElem, destExpr, destLen, destElem, dest)
+			}
+		}
+		if elements, ok := g.sliceElements(mapping, sourceType, dest); ok {
+			assignment, hasError := g.sliceAssignment(mapping, sourceExpr, destExpr, dest, elements)
+			return assignment, hasError, nil
+		}
+	}
+	// funcs of identical types are copied like any other value, sharing the function
+	sameFunc := dest.Kind == FieldKindFunc && sourceType.Equals(dest.TypeWithImportsTemplate)
+	if conversion == nil && !sameFunc && (dest.Kind.Unsupported() || (source != nil && source.Kind.Unsupported())) {
+		kind := dest.Kind
+		if !kind.Unsupported() {
+			kind = source.Kind
+		}
+		if g.config.Strict {
+			return "", false, fmt.Errorf("unsupported field kind %s for field %s, add a conversion for this type pair", kind, dest.Name)
+		}
+		fieldReport.MatchedBy = MatchKindSkipped
+		return fmt.Sprintf("// skipped unsupported field kind %s for field: %s", kind, dest.Name), false, nil
+	}
+	if conversion == nil && (dest.Kind == FieldKindInterface || (source != nil && source.Kind == FieldKindInterface)) {
+		if err := g.checkInterfaceAssignment(sourceType, dest); err != nil {
+			return "", false, err
+		}
+	}
+	if conversion != nil {
+		fieldReport.Conversion = g.describeConversion(conversion, isReverse)
+	}
+	if mapping.ZeroToNil && pointerTo(sourceType, dest.TypeWithImportsTemplate) {
+		assignment, hasError := g.zeroToNilAssignment(mapping, sourceExpr, sourceType, destExpr, dest, conversion, isReverse)
+		return assignment, hasError, nil
 	}
+	assignment, hasError := g.assignmentWithConversion(mapping, sourceExpr, sourceType, destExpr, dest, conversion, isReverse)
+	return assignment, hasError, nil
 }
 
-func (g *Generator) assignmentWithConversion(sourceExpr string, dest FieldDefinition, conversion *Conversion, isReverse bool) (string, bool) {
-	destExpr := fmt.Sprintf("dst.%s", dest.Name)
-	errorExpr := "err"
+func (g *Generator) checkInterfaceAssignment(sourceType TypeWithImportsTemplate, dest FieldDefinition) error {
+	renderedSource := sourceType.ExecuteTemplate(g.importManager)
+	renderedDest := dest.ExecuteTemplate(g.importManager)
+	if renderedSource == renderedDest || (isEmptyInterface(renderedSource) && isEmptyInterface(renderedDest)) {
+		return nil
+	}
+	return fmt.Errorf("unsupported assignment of interface field %s from %s to %s, add a conversion for this type pair", dest.Name, renderedSource, renderedDest)
+}
+
+func (g *Generator) compositeAssignment(
+	mapping Mapping,
+	composite CustomFieldMapping,
+	dest FieldDefinition,
+	byName map[string]FieldDefinition,
+) (string, error) {
+	sourceExprs := make([]string, len(composite.SourceFields))
+	for idx, name := range composite.SourceFields {
+		field, ok := byName[name]
+		if !ok {
+			return "", fmt.Errorf("custom field mapping for %s: source field %s not found in %s", dest.Name, name, mapping.primary().GetUnaliasedType())
+		}
+		sourceExprs[idx] = g.srcVar(mapping) + "." + field.Name
+	}
+	return composite.ExecuteCompositeTemplate(sourceExprs, g.config.DstVar()+"."+dest.Name, mapping.AdditionalArgNames(), g.importManager)
+}
+
+func (g *Generator) assignmentWithConversion(
+	mapping Mapping,
+	sourceExpr string,
+	sourceType TypeWithImportsTemplate,
+	destExpr string,
+	dest FieldDefinition,
+	conversion *Conversion,
+	isReverse bool,
+) (string, bool) {
+	errorExpr := g.config.ErrVar()
 	if conversion != nil {
+		if conversion.listIdx > 0 {
+			g.usedConversions[conversion.listIdx-1] = true
+		}
+		conversion = g.useFuncConversion(conversion, isReverse)
+		for _, imp := range conversion.TemplateImports(isReverse) {
+			g.importManager.AddImport(imp)
+		}
+		convSourceType, convDestType := conversion.GetSourceTypeWithImportsTemplate(), conversion.GetDestTypeWithImportsTemplate()
+		if isReverse {
+			convSourceType, convDestType = convDestType, convSourceType
+		}
+		renderedSourceType := sourceType.ExecuteTemplate(g.importManager)
+		renderedDestType := dest.ExecuteTemplate(g.importManager)
+		renderedConvSourceType := convSourceType.ExecuteTemplate(g.importManager)
+		renderedConvDestType := convDestType.ExecuteTemplate(g.importManager)
+		if renderedConvSourceType != renderedSourceType {
+			sourceExpr = fmt.Sprintf("%s(%s)", renderedConvSourceType, sourceExpr)
+		}
+		conversionTemplate := conversion.Conversion
+		if isReverse {
+			conversionTemplate = conversion.ReverseConversion
+		}
+		convertedExpr := destExpr
+		if renderedConvDestType != renderedDestType || conversionTemplate.OnNotOk != "" {
+			convertedExpr = "converted"
+		}
+		templateData := ConversionTemplateData{
+			Source:     sourceExpr,
+			Dest:       convertedExpr,
+			Error:      errorExpr,
+			FieldName:  dest.Name,
+			SourceType: renderedConvSourceType,
+			DestType:   renderedConvDestType,
+			Ctx:        contextVar,
+			SrcStruct:  g.srcVar(mapping),
+			Args:       mapping.AdditionalArgNames(),
+		}
+		if conversionTemplate.OnNotOk != "" {
+			templateData.Ok = "ok"
+		}
+		if conversion.NeedsContext {
+			g.needsContext = true
+		}
+		var assignment string
+		var hasError bool
 		if isReverse {
-			return conversion.ExecuteReverseConversionTemplate(sourceExpr, destExpr, errorExpr, g.importManager)
+			assignment, hasError = conversion.ExecuteReverseConversionTemplate(templateData, g.importManager)
 		} else {
-			return conversion.ExecuteConversionTemplate(sourceExpr, destExpr, errorExpr, g.importManager)
+			assignment, hasError = conversion.ExecuteConversionTemplate(templateData, g.importManager)
+		}
+		if templateData.Ok != "" {
+			return g.okAssignment(conversion, isReverse, conversionTemplate, templateData, assignment, hasError, destExpr, dest, convDestType)
+		}
+		if convertedExpr != destExpr {
+			assignment = fmt.Sprintf(`{
+		var %s %s
+		%s
+		%s = %s(%s)
+	}`, convertedExpr, renderedConvDestType, assignment, destExpr, renderedDestType, convertedExpr)
+		}
+		if hasError {
+			assignment += "\n\t" + g.errorCheck(dest, errorExpr)
+		}
+		return assignment, hasError
+	}
+	if mapping.DeepCopy && sourceType.Equals(dest.TypeWithImportsTemplate) {
+		if assignment, ok := g.deepCopyAssignment(sourceExpr, destExpr, dest); ok {
+			return assignment, false
 		}
 	}
+	if !sourceType.Equals(dest.TypeWithImportsTemplate) && (g.isNumericWidening(sourceType, dest.TypeWithImportsTemplate) || g.needsTypeConversion(sourceType, dest.TypeWithImportsTemplate)) {
+		sourceExpr = fmt.Sprintf("%s(%s)", dest.ExecuteTemplate(g.importManager), sourceExpr)
+	}
 	return fmt.Sprintf("%s = %s", destExpr, sourceExpr), false
 }
 
+// okAssignment renders a conversion that also assigns an ok result: the value is converted into
+// a temporary and only stored in the dest when ok is true, otherwise on_not_ok leaves the dest
+// untouched, assigns the default or fails the mapping.
+func (g *Generator) okAssignment(
+	conversion *Conversion,
+	isReverse bool,
+	conversionTemplate ConversionTemplate,
+	templateData ConversionTemplateData,
+	assignment string,
+	hasError bool,
+	destExpr string,
+	dest FieldDefinition,
+	convDestType TypeWithImportsTemplate,
+) (string, bool) {
+	renderedConvDestType := convDestType.ExecuteTemplate(g.importManager)
+	lines := []string{
+		fmt.Sprintf("var %s %s", templateData.Dest, renderedConvDestType),
+		fmt.Sprintf("var %s bool", templateData.Ok),
+		assignment,
+	}
+	if hasError {
+		lines = append(lines, g.errorCheck(dest, templateData.Error))
+	}
+	store := fmt.Sprintf("%s = %s", destExpr, templateData.Dest)
+	if renderedDestType := dest.ExecuteTemplate(g.importManager); renderedDestType != renderedConvDestType {
+		store = fmt.Sprintf("%s = %s(%s)", destExpr, renderedDestType, templateData.Dest)
+	}
+	switch conversionTemplate.OnNotOk {
+	case NotOkSkip:
+		lines = append(lines, fmt.Sprintf(`if %s {
+		%s
+	}`, templateData.Ok, store))
+	case NotOkDefault:
+		defaultValue := g.zeroValue(dest.TypeWithImportsTemplate)
+		if conversionTemplate.Default != "" {
+			defaultValue = conversion.ExecuteDefaultTemplate(isReverse, templateData, g.importManager)
+		}
+		lines = append(lines, fmt.Sprintf(`if %s {
+		%s
+	} else {
+		%s = %s
+	}`, templateData.Ok, store, destExpr, defaultValue))
+	case NotOkError:
+		fmtAlias := g.importManager.AddStdImport("fmt")
+		errorValue := g.errorValue(fmt.Sprintf("%s.Errorf(\"field %%q: can't convert %%v to %s\", %q, %s)", fmtAlias, convDestType.GetQualifiedType(g.packageName), dest.Name, templateData.Source))
+		lines = append(lines, fmt.Sprintf(`if !%s {
+		%s = %s
+		return
+	}`, templateData.Ok, templateData.Error, errorValue), store)
+		hasError = true
+	}
+	return fmt.Sprintf(`{
+		%s
+	}`, strings.Join(lines, "\n\t\t")), hasError
+}
+
+// numericWidenings lists, per predeclared numeric type, the types that hold every one of its
+// values exactly. int and uint are assumed to be at least 32 and at most 64 bits wide.
+var numericWidenings = map[string][]string{
+	"int8":      {"int16", "int32", "int64", "int", "float32", "float64"},
+	"int16":     {"int32", "int64", "int", "float32", "float64"},
+	"int32":     {"int64", "int", "float64"},
+	"int":       {"int64"},
+	"uint8":     {"uint16", "uint32", "uint64", "uint", "int16", "int32", "int64", "int", "float32", "float64"},
+	"uint16":    {"uint32", "uint64", "uint", "int32", "int64", "int", "float32", "float64"},
+	"uint32":    {"uint64", "uint", "int64", "float64"},
+	"uint":      {"uint64"},
+	"float32":   {"float64"},
+	"complex64": {"complex128"},
+}
+
+// isNumericWidening reports whether the source converts to the dest without loss,
+// following named types to their predeclared underlying types.
+func (g *Generator) isNumericWidening(sourceType TypeWithImportsTemplate, destType TypeWithImportsTemplate) bool {
+	source := canonicalNumericType(g.underlyingBasicType(sourceType).TypeTemplate)
+	dest := canonicalNumericType(g.underlyingBasicType(destType).TypeTemplate)
+	return slices.Contains(numericWidenings[source], dest)
+}
+
+func canonicalNumericType(typ string) string {
+	switch typ {
+	case "byte":
+		return "uint8"
+	case "rune":
+		return "int32"
+	}
+	return typ
+}
+
+// needsTypeConversion reports whether two distinct named types, e.g. `type Hobbies []string`
+// and `type Tags []string`, share an underlying type built only from predeclared types.
+// A named and an unnamed type with such an underlying type are assignable as they are.
+func (g *Generator) needsTypeConversion(sourceType TypeWithImportsTemplate, destType TypeWithImportsTemplate) bool {
+	sourceUnderlying, ok := g.underlyingType(sourceType)
+	if !ok || !predeclaredOnly(sourceUnderlying) {
+		return false
+	}
+	destUnderlying, ok := g.underlyingType(destType)
+	if !ok || !predeclaredOnly(destUnderlying) {
+		return false
+	}
+	if renderExpr(sourceUnderlying) != renderExpr(destUnderlying) {
+		return false
+	}
+	return isNamedType(sourceType.ExecuteTemplate(g.importManager)) && isNamedType(destType.ExecuteTemplate(g.importManager))
+}
+
+func predeclaredOnly(expression ast.Expr) bool {
+	result := true
+	ast.Inspect(expression, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.SelectorExpr:
+			result = false
+		case *ast.Ident:
+			if types.Universe.Lookup(e.Name) == nil {
+				result = false
+			}
+		}
+		return result
+	})
+	return result
+}
+
+func isNamedType(typ string) bool {
+	expression, err := parser.ParseExpr(typ)
+	if err != nil {
+		return false
+	}
+	switch e := expression.(type) {
+	case *ast.IndexExpr:
+		expression = e.X
+	case *ast.IndexListExpr:
+		expression = e.X
+	}
+	switch expression.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		return true
+	}
+	return false
+}
+
+func renderExpr(expression ast.Expr) string {
+	var buf strings.Builder
+	printer.Fprint(&buf, token.NewFileSet(), expression)
+	return buf.String()
+}
+
+func (g *Generator) deepCopyAssignment(sourceExpr string, destExpr string, dest FieldDefinition) (string, bool) {
+	expression, ok := g.underlyingType(dest.TypeWithImportsTemplate)
+	if !ok {
+		return "", false
+	}
+	renderedType := dest.ExecuteTemplate(g.importManager)
+	switch e := expression.(type) {
+	case *ast.ArrayType:
+		if e.Len != nil {
+			return "", false
+		}
+		return fmt.Sprintf(`if %s != nil {
+		%s = make(%s, len(%s))
+		copy(%s, %s)
+	}`, sourceExpr, destExpr, renderedType, sourceExpr, destExpr, sourceExpr), true
+	case *ast.MapType:
+		return fmt.Sprintf(`if %s != nil {
+		%s = make(%s, len(%s))
+		for k, v := range %s {
+			%s[k] = v
+		}
+	}`, sourceExpr, destExpr, renderedType, sourceExpr, sourceExpr, destExpr), true
+	}
+	return "", false
+}
+
+// errorTypeName renders the type of the err result, error unless error_type is set.
+func (g *Generator) errorTypeName() string {
+	if g.config.ErrorType == nil {
+		return "error"
+	}
+	return g.config.ErrorType.ExecuteTemplate(g.importManager)
+}
+
+// errorValue converts an error created by the generated code into the configured error type.
+func (g *Generator) errorValue(errorExpr string) string {
+	if g.config.ErrorType == nil {
+		return errorExpr
+	}
+	if g.config.ErrorType.Wrap == "" {
+		g.errorWrapMissing = true
+		return errorExpr
+	}
+	// the template is parsed before generating, so it can't fail here
+	wrapped, _ := g.config.ErrorType.ExecuteWrapTemplate(errorExpr, g.importManager)
+	return wrapped
+}
+
+func (g *Generator) errorCheck(dest FieldDefinition, errorExpr string) string {
+	if g.config.WrapConversionErrors {
+		fmtAlias := g.importManager.AddStdImport("fmt")
+		return fmt.Sprintf(`if %s != nil {
+		%s = %s
+		return
+	}`, errorExpr, errorExpr, g.errorValue(fmt.Sprintf("%s.Errorf(\"field %%q: %%w\", %q, %s)", fmtAlias, dest.Name, errorExpr)))
+	}
+	return fmt.Sprintf(`if %s != nil {
+		return
+	}`, errorExpr)
+}
+
+// skipsConversionLookup reports whether findConversion can return early: identical templates,
+// the common case for large mappings, can only be converted by conversions declared between
+// that very type or by apply_to_same_type ones, so the lookup is skipped when there are none.
+func (g *Generator) skipsConversionLookup(source TypeWithImportsTemplate, dest TypeWithImportsTemplate, mapping Mapping) bool {
+	if g.config.tracesConversions() || g.appliesToSameType {
+		return false
+	}
+	if source.TypeTemplate != dest.TypeTemplate || !slices.Equal(source.Imports, dest.Imports) || g.sameTypeConversions[source.key()] {
+		return false
+	}
+	return !slices.ContainsFunc(mapping.CustomConversions, func(conv Conversion) bool {
+		return conv.ApplyToSameType || conv.GetSourceTypeWithImportsTemplate().Equals(conv.GetDestTypeWithImportsTemplate())
+	})
+}
+
 func (g *Generator) findConversion(
 	sourceTypeTemplate TypeWithImportsTemplate,
 	destTypeTemplate TypeWithImportsTemplate,
-	conversions []Conversion,
-	customConversions []Conversion,
+	fieldName string,
+	mapping Mapping,
 ) (*Conversion, bool) {
-	equalsFunc := func(conv Conversion, sourceTypeTemplate TypeWithImportsTemplate, destTypeTemplate TypeWithImportsTemplate) bool {
-		return conv.GetSourceTypeWithImportsTemplate().Equals(sourceTypeTemplate, g.importManager) && conv.GetDestTypeWithImportsTemplate().Equals(destTypeTemplate, g.importManager)
+	if g.skipsConversionLookup(sourceTypeTemplate, destTypeTemplate, mapping) {
+		return nil, false
 	}
-	reverseEqualsFunc := func(conv Conversion, sourceTypeTemplate TypeWithImportsTemplate, destTypeTemplate TypeWithImportsTemplate) bool {
-		return conv.GetDestTypeWithImportsTemplate().Equals(sourceTypeTemplate, g.importManager) && conv.GetSourceTypeWithImportsTemplate().Equals(destTypeTemplate, g.importManager) && conv.ReverseConversion.Tmpl != ""
+	trace := g.newConversionTrace(sourceTypeTemplate, destTypeTemplate, fieldName)
+	defer g.logConversionTrace(trace)
+
+	sameType := sourceTypeTemplate.Equals(destTypeTemplate)
+	compare := func(list string, idx int, conv Conversion, sourceTypeTemplate TypeWithImportsTemplate, destTypeTemplate TypeWithImportsTemplate) (bool, bool) {
+		// type patterns are matched once no conversion of the exact types applies
+		if conv.hasTypePattern() {
+			return false, false
+		}
+		forward := conversionMismatch(conv, sourceTypeTemplate, destTypeTemplate, false)
+		reverse := conversionMismatch(conv, sourceTypeTemplate, destTypeTemplate, true)
+		if trace != nil {
+			trace.compare(g.conversionLabel(list, idx, conv), forward, reverse)
+		}
+		return forward == "" || reverse == "", forward != ""
 	}
-	for _, conv := range customConversions {
-		if equalsFunc(conv, sourceTypeTemplate, destTypeTemplate) {
-			return &conv, false
+	// field-scoped conversions take precedence over the ones matching any field
+	for _, scoped := range []bool{true, false} {
+		scopeFunc := func(list string, idx int, conv Conversion) bool {
+			if scoped {
+				return conv.scoped() && conv.inScope(fieldName, g.fieldTags)
+			}
+			if trace != nil && conv.scoped() && !conv.inScope(fieldName, g.fieldTags) {
+				trace.addf("  %s: skipped, scoped to other fields", g.conversionLabel(list, idx, conv))
+			}
+			return !conv.scoped()
+		}
+		for idx, conv := range mapping.CustomConversions {
+			if !scopeFunc("custom_conversions", idx, conv) {
+				continue
+			}
+			if matched, isReverse := compare("custom_conversions", idx, conv, sourceTypeTemplate, destTypeTemplate); matched {
+				return &conv, isReverse
+			}
 		}
-		if reverseEqualsFunc(conv, sourceTypeTemplate, destTypeTemplate) {
-			return &conv, true
+		for idx, conv := range g.conversions.Conversions {
+			if !scopeFunc("conversions", idx, conv) {
+				continue
+			}
+			if g.conversionDisabled(conv, mapping) {
+				if trace != nil {
+					trace.addf("  %s: skipped, disabled by the mapping", g.conversionLabel("conversions", idx, conv))
+				}
+				continue
+			}
+			if matched, isReverse := compare("conversions", idx, conv, sourceTypeTemplate, destTypeTemplate); matched {
+				return &conv, isReverse
+			}
 		}
 	}
-	for _, conv := range conversions {
-		if equalsFunc(conv, sourceTypeTemplate, destTypeTemplate) {
-			return &conv, false
+
+	if conv, isReverse := g.findPatternConversion(sourceTypeTemplate, destTypeTemplate, fieldName, mapping, sameType, trace); conv != nil {
+		return conv, isReverse
+	}
+
+	sourceUnderlying := g.underlyingBasicType(sourceTypeTemplate)
+	destUnderlying := g.underlyingBasicType(destTypeTemplate)
+	if sourceUnderlying.Equals(sourceTypeTemplate) && destUnderlying.Equals(destTypeTemplate) {
+		return nil, false
+	}
+	trace.addf("  underlying types: %s → %s", sourceUnderlying.key(), destUnderlying.key())
+	for idx, conv := range append(append([]Conversion{}, mapping.CustomConversions...), g.conversions.Conversions...) {
+		if !conv.MatchUnderlying || (conv.scoped() && !conv.inScope(fieldName, g.fieldTags)) {
+			continue
+		}
+		// like type patterns, underlying types only widen to fields of the same type on request
+		if sameType && !conv.ApplyToSameType {
+			continue
+		}
+		if idx >= len(mapping.CustomConversions) && g.conversionDisabled(conv, mapping) {
+			continue
+		}
+		list, listIdx := "custom_conversions", idx
+		if idx >= len(mapping.CustomConversions) {
+			list, listIdx = "conversions", idx-len(mapping.CustomConversions)
 		}
-		if reverseEqualsFunc(conv, sourceTypeTemplate, destTypeTemplate) {
-			return &conv, true
+		matched, isReverse := compare(list, listIdx, conv, sourceUnderlying, destUnderlying)
+		if !matched {
+			continue
 		}
+		return &conv, isReverse
 	}
 	return nil, false
 }
 
-func (g *Generator) findStructDefinition(pkgPath string, typeName string) (*ast.StructType, string, error) {
+func (g *Generator) findStructDefinition(pkgPath string, typeName string) (*ast.TypeSpec, string, error) {
 	visited := map[string]bool{}
 	return g.findStructDefinitionRecursive(pkgPath, typeName, visited)
 }
@@ -499,7 +2563,7 @@ func (g *Generator) findStructDefinitionRecursive(
 	pkgPath string,
 	typeName string,
 	visited map[string]bool,
-) (*ast.StructType, string, error) {
+) (*ast.TypeSpec, string, error) {
 	key := fmt.Sprintf("%s.%s", pkgPath, typeName)
 	if visited[key] {
 		return nil, "", fmt.Errorf("circular type alias detected: %s", key)
@@ -511,14 +2575,9 @@ func (g *Generator) findStructDefinitionRecursive(
 		return nil, "", fmt.Errorf("failed to load package %s: %w", pkgPath, err)
 	}
 
-	fset := token.NewFileSet()
-	for _, file := range pkg.GoFiles {
-		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
-		if err != nil {
-			continue
-		}
+	for _, f := range packages.Files(pkg) {
 
-		var foundStruct *ast.StructType
+		var foundStruct *ast.TypeSpec
 		var foundPkgPath string
 		var foundErr error
 
@@ -531,47 +2590,36 @@ func (g *Generator) findStructDefinitionRecursive(
 				return true
 			}
 
-			switch t := ts.Type.(type) {
+			target := ts.Type
+			if paren, ok := target.(*ast.ParenExpr); ok {
+				target = paren.X
+			}
+
+			switch t := target.(type) {
 			case *ast.StructType:
-				foundStruct = t
+				foundStruct = ts
 				foundPkgPath = pkgPath
 				return false
-			case *ast.Ident:
-				aliasTypeName := t.Name
-
-				if strings.Contains(aliasTypeName, ".") {
-					parts := strings.Split(aliasTypeName, ".")
-					if len(parts) != 2 {
-						foundErr = fmt.Errorf("invalid qualified type: %s", aliasTypeName)
-						return false
-					}
-					importPkgPath := parts[0]
-					importTypeName := parts[1]
-
-					importInfo, err := g.findImportSpecForAlias(f, importPkgPath)
-					if err != nil {
-						foundErr = fmt.Errorf("import path not found for %s", importPkgPath)
-						return false
-					}
-
-					recursiveStruct, recursivePkgPath, recursiveErr := g.findStructDefinitionRecursive(importInfo.Path, importTypeName, visited)
-					if recursiveErr != nil {
-						foundErr = recursiveErr
-						return false
-					}
-					foundStruct = recursiveStruct
-					foundPkgPath = recursivePkgPath
+			case *ast.Ident:
+				foundStruct, foundPkgPath, foundErr = g.findStructDefinitionRecursive(pkgPath, t.Name, visited)
+				return false
+			case *ast.SelectorExpr:
+				pkgIdent, ok := t.X.(*ast.Ident)
+				if !ok {
+					foundErr = fmt.Errorf("unsupported qualified type for %s", key)
 					return false
-				} else {
-					recursiveStruct, recursivePkgPath, recursiveErr := g.findStructDefinitionRecursive(pkgPath, aliasTypeName, visited)
-					if recursiveErr != nil {
-						foundErr = recursiveErr
-						return false
-					}
-					foundStruct = recursiveStruct
-					foundPkgPath = recursivePkgPath
+				}
+				importInfo, err := g.findImportSpecForAlias(f, pkgIdent.Name)
+				if err != nil {
+					foundErr = err
 					return false
 				}
+				if importInfo == nil {
+					foundErr = fmt.Errorf("import path not found for %s", pkgIdent.Name)
+					return false
+				}
+				foundStruct, foundPkgPath, foundErr = g.findStructDefinitionRecursive(importInfo.Path, t.Sel.Name, visited)
+				return false
 			}
 			return true
 		})
@@ -584,7 +2632,68 @@ func (g *Generator) findStructDefinitionRecursive(
 		}
 	}
 
-	return nil, "", fmt.Errorf("type %s not found in package %s", typeName, pkgPath)
+	return nil, "", fmt.Errorf("%w: %s in package %s", ErrTypeNotFound, typeName, pkgPath)
+}
+
+func (g *Generator) findTypeSpec(pkgPath string, typeName string) (*ast.TypeSpec, error) {
+	pkg, err := g.packageManager.GetPackage(pkgPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package %s: %w", pkgPath, err)
+	}
+
+	for _, f := range packages.Files(pkg) {
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == typeName {
+					return ts, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s in package %s", ErrTypeNotFound, typeName, pkgPath)
+}
+
+func (g *Generator) fieldKind(expression ast.Expr, pkgPath string) FieldKind {
+	switch e := expression.(type) {
+	case *ast.InterfaceType:
+		return FieldKindInterface
+	case *ast.ChanType:
+		return FieldKindChan
+	case *ast.FuncType:
+		return FieldKindFunc
+	case *ast.Ident:
+		if e.Name == "error" || e.Name == "any" {
+			return FieldKindInterface
+		}
+		if types.Universe.Lookup(e.Name) != nil {
+			return FieldKindDefault
+		}
+	case *ast.SelectorExpr:
+	default:
+		return FieldKindDefault
+	}
+	typePkgPath, typeName, err := g.resolveTypeForEmbeddedField(expression, pkgPath)
+	if err != nil {
+		return FieldKindDefault
+	}
+	if typePkgPath == "unsafe" && typeName == "Pointer" {
+		return FieldKindUnsafePointer
+	}
+	if typePkgPath == "sync" {
+		switch typeName {
+		case "Mutex", "RWMutex", "WaitGroup", "Once", "Cond":
+			return FieldKindLock
+		}
+	}
+	ts, err := g.findTypeSpec(typePkgPath, typeName)
+	if err != nil {
+		return FieldKindDefault
+	}
+	return g.fieldKind(ts.Type, typePkgPath)
 }
 
 func (g *Generator) findImportSpecForAlias(f *ast.File, pkgAlias string) (*ImportInfo, error) {
@@ -629,14 +2738,9 @@ func (g *Generator) findImportSpecsForExpression(expression ast.Expr, pkgPath st
 		return nil, fmt.Errorf("failed to load package %s: %w", pkgPath, err)
 	}
 
-	fset := token.NewFileSet()
 	for _, pkgAlias := range pkgAliases {
 		found := false
-		for _, gofile := range pkg.GoFiles {
-			file, err := parser.ParseFile(fset, gofile, nil, parser.ParseComments)
-			if err != nil {
-				continue
-			}
+		for _, file := range packages.Files(pkg) {
 			importInfo, err := g.findImportSpecForAlias(file, pkgAlias)
 			if err != nil {
 				return nil, err
@@ -660,6 +2764,10 @@ func (g *Generator) resolveTypeForEmbeddedField(expression ast.Expr, currentPkgP
 	switch e := expression.(type) {
 	case *ast.StarExpr:
 		return g.resolveTypeForEmbeddedField(e.X, currentPkgPath)
+	case *ast.IndexExpr:
+		return g.resolveTypeForEmbeddedField(e.X, currentPkgPath)
+	case *ast.IndexListExpr:
+		return g.resolveTypeForEmbeddedField(e.X, currentPkgPath)
 	case *ast.Ident:
 		return currentPkgPath, e.Name, nil
 	case *ast.SelectorExpr:
@@ -671,12 +2779,7 @@ func (g *Generator) resolveTypeForEmbeddedField(expression ast.Expr, currentPkgP
 		if err != nil {
 			return "", "", fmt.Errorf("failed to load package %s: %w", currentPkgPath, err)
 		}
-		fset := token.NewFileSet()
-		for _, gofile := range pkg.GoFiles {
-			file, err := parser.ParseFile(fset, gofile, nil, parser.ParseComments)
-			if err != nil {
-				continue
-			}
+		for _, file := range packages.Files(pkg) {
 			importInfo, err := g.findImportSpecForAlias(file, ident.Name)
 			if err != nil {
 				return "", "", err
@@ -691,12 +2794,332 @@ func (g *Generator) resolveTypeForEmbeddedField(expression ast.Expr, currentPkgP
 	}
 }
 
-func (g *Generator) expandEmbeddedFields(fld *ast.Field, structPkgPath string) ([]FieldDefinition, error) {
+// expandEmbeddedFields returns the fields promoted by an embedded struct. For an instantiation
+// of a generic struct, like `Base[int]` or `Base[T]` in a generic struct, the type arguments
+// are resolved in the embedding struct and substituted into the promoted fields.
+func (g *Generator) expandEmbeddedFields(fld *ast.Field, structPkgPath string, structPkgName string, typeParams map[string]string, typeParamArgs map[string]TypeWithImportsTemplate, embeddingPath []string) ([]FieldDefinition, error) {
 	pkgPath, typeName, err := g.resolveTypeForEmbeddedField(fld.Type, structPkgPath)
 	if err != nil {
 		return nil, err
 	}
-	return g.extractFieldsFromPackage(pkgPath, typeName)
+	var typeArgs []TypeWithImportsTemplate
+	for _, index := range embeddedTypeArgs(fld.Type) {
+		argType, err := g.qualifyDotImports(index, structPkgPath, typeParams)
+		if err != nil {
+			return nil, err
+		}
+		typeArg, err := g.typeTemplate(argType, structPkgPath, structPkgName, typeParams, typeParamArgs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve type argument of embedded %s: %w", typeName, err)
+		}
+		typeArgs = append(typeArgs, typeArg)
+	}
+	return g.extractFields(pkgPath, typeName, typeArgs, embeddingPath)
+}
+
+func embeddedTypeArgs(expression ast.Expr) []ast.Expr {
+	switch e := expression.(type) {
+	case *ast.StarExpr:
+		return embeddedTypeArgs(e.X)
+	case *ast.IndexExpr:
+		return []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		return e.Indices
+	}
+	return nil
+}
+
+func (g *Generator) zeroValue(t TypeWithImportsTemplate) string {
+	rendered := t.ExecuteTemplate(g.importManager)
+	expression, ok := g.underlyingType(t)
+	if !ok {
+		return fmt.Sprintf("*new(%s)", rendered)
+	}
+	switch e := expression.(type) {
+	case *ast.Ident:
+		switch e.Name {
+		case "string":
+			return `""`
+		case "bool":
+			return "false"
+		case "any", "error":
+			return "nil"
+		}
+		if obj := types.Universe.Lookup(e.Name); obj != nil {
+			if basic, ok := obj.Type().(*types.Basic); ok && basic.Info()&types.IsNumeric != 0 {
+				return "0"
+			}
+		}
+	case *ast.ArrayType:
+		if e.Len == nil {
+			return "nil"
+		}
+		return rendered + "{}"
+	case *ast.StructType:
+		return rendered + "{}"
+	case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType:
+		return "nil"
+	}
+	return fmt.Sprintf("*new(%s)", rendered)
+}
+
+func (g *Generator) nonZeroCondition(expr string, t TypeWithImportsTemplate) string {
+	if expression, ok := g.underlyingType(t); ok {
+		switch e := expression.(type) {
+		case *ast.Ident:
+			if e.Name == "bool" {
+				return expr
+			}
+		case *ast.ArrayType:
+			if e.Len == nil {
+				return fmt.Sprintf("len(%s) != 0", expr)
+			}
+		case *ast.MapType:
+			return fmt.Sprintf("len(%s) != 0", expr)
+		}
+	}
+	zero := g.zeroValue(t)
+	if strings.HasSuffix(zero, "}") {
+		// structs and arrays holding slices, maps or funcs can't be compared with their zero value
+		if !g.comparable(t) {
+			return fmt.Sprintf("!%s.ValueOf(%s).IsZero()", g.importManager.AddStdImport("reflect"), expr)
+		}
+		zero = "(" + zero + ")"
+	}
+	return fmt.Sprintf("%s != %s", expr, zero)
+}
+
+// comparable reports whether values of the struct or array type t can be compared with ==, as
+// far as the type checker can tell, types it can't check count as not comparable.
+func (g *Generator) comparable(t TypeWithImportsTemplate) bool {
+	if _, elem, ok := arrayType(t); ok {
+		return g.comparable(elem)
+	}
+	if obj := types.Universe.Lookup(strings.TrimSpace(t.TypeTemplate)); obj != nil {
+		return types.Comparable(obj.Type())
+	}
+	pkgPath, typeName, typeArgs, err := t.SplitTypeArgs()
+	if err != nil || len(typeArgs) > 0 || pkgPath == "" {
+		return false
+	}
+	obj := g.packageManager.LookupType(pkgPath, typeName)
+	return obj != nil && types.Comparable(obj.Type())
+}
+
+func (g *Generator) underlyingBasicType(t TypeWithImportsTemplate) TypeWithImportsTemplate {
+	expression, ok := g.underlyingType(t)
+	if !ok {
+		return t
+	}
+	ident, ok := expression.(*ast.Ident)
+	if !ok {
+		return t
+	}
+	if _, ok := types.Universe.Lookup(ident.Name).(*types.TypeName); !ok {
+		return t
+	}
+	return NewTypeWithImportsTemplate(ident.Name, nil)
+}
+
+// underlyingType follows named types down to the type expression they're defined with.
+func (g *Generator) underlyingType(t TypeWithImportsTemplate) (ast.Expr, bool) {
+	expression, err := parser.ParseExpr(t.ExecuteTemplate(g.importManager))
+	if err != nil {
+		return nil, false
+	}
+	pkgPath := ""
+	visited := map[string]bool{}
+	for {
+		var typePkgPath, typeName string
+		switch e := expression.(type) {
+		case *ast.ParenExpr:
+			expression = e.X
+			continue
+		case *ast.IndexExpr:
+			expression = e.X
+			continue
+		case *ast.IndexListExpr:
+			expression = e.X
+			continue
+		case *ast.Ident:
+			if pkgPath == "" || types.Universe.Lookup(e.Name) != nil {
+				return expression, true
+			}
+			typePkgPath, typeName = pkgPath, e.Name
+		case *ast.SelectorExpr:
+			var err error
+			if pkgPath == "" {
+				typePkgPath, typeName, err = g.resolveTemplateSelector(t, e)
+			} else {
+				typePkgPath, typeName, err = g.resolveTypeForEmbeddedField(e, pkgPath)
+			}
+			if err != nil {
+				return nil, false
+			}
+		default:
+			return expression, true
+		}
+		key := typePkgPath + "." + typeName
+		if visited[key] {
+			return nil, false
+		}
+		visited[key] = true
+		ts, err := g.findTypeSpec(typePkgPath, typeName)
+		if err != nil {
+			return nil, false
+		}
+		expression, pkgPath = ts.Type, typePkgPath
+	}
+}
+
+func (g *Generator) resolveTemplateSelector(t TypeWithImportsTemplate, e *ast.SelectorExpr) (string, string, error) {
+	ident, ok := e.X.(*ast.Ident)
+	if !ok {
+		return "", "", fmt.Errorf("unsupported selector expression")
+	}
+	for _, imp := range t.Imports {
+		if g.importManager.GetImportAlias(imp) == ident.Name {
+			return imp, e.Sel.Name, nil
+		}
+	}
+	return "", "", fmt.Errorf("import not found for package %s", ident.Name)
+}
+
+// qualifyDotImports qualifies the unqualified type names of expression that aren't declared in
+// pkgPath, but in a package one of its files dot-imports, with that package's name, so they're
+// resolved through its import like any other qualified type.
+func (g *Generator) qualifyDotImports(expression ast.Expr, pkgPath string, typeParams map[string]string) (ast.Expr, error) {
+	pkg, err := g.packageManager.GetPackage(pkgPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package %s: %w", pkgPath, err)
+	}
+	var dotImports []string
+	for _, f := range packages.Files(pkg) {
+		for _, imp := range f.Imports {
+			if imp.Name != nil && imp.Name.Name == "." {
+				dotImports = append(dotImports, strings.Trim(imp.Path.Value, "\""))
+			}
+		}
+	}
+	if len(dotImports) == 0 {
+		return expression, nil
+	}
+	var resolveErr error
+	result := astutil.Apply(cloneExpr(expression), func(c *astutil.Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ast.SelectorExpr:
+			return false
+		case *ast.FuncType:
+			// param names aren't part of a func type, so they're dropped to let func(id int) and
+			// func(int) compare equal
+			n.Params.List = unnamedFields(n.Params.List)
+			if n.Results != nil {
+				n.Results.List = unnamedFields(n.Results.List)
+			}
+		case *ast.Ident:
+			if _, ok := c.Parent().(*ast.Field); ok && c.Name() == "Names" {
+				return false
+			}
+			if _, ok := typeParams[n.Name]; ok || types.Universe.Lookup(n.Name) != nil || n.Name == "_" {
+				return false
+			}
+			if _, err := g.findTypeSpec(pkgPath, n.Name); err == nil {
+				return false
+			}
+			for _, dotImport := range dotImports {
+				if _, err := g.findTypeSpec(dotImport, n.Name); err != nil {
+					continue
+				}
+				dotPkg, err := g.packageManager.GetPackage(dotImport)
+				if err != nil {
+					resolveErr = fmt.Errorf("failed to load package %s: %w", dotImport, err)
+					return false
+				}
+				c.Replace(&ast.SelectorExpr{X: ast.NewIdent(dotPkg.Name), Sel: ast.NewIdent(n.Name)})
+				return false
+			}
+			return false
+		}
+		return true
+	}, nil)
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	return result.(ast.Expr), nil
+}
+
+func qualifyLocalIdents(expression ast.Expr, pkgName string, typeParams map[string]string) (ast.Expr, bool) {
+	qualified := false
+	// syntax trees are shared through the package cache, so rewrite a copy
+	result := astutil.Apply(cloneExpr(expression), func(c *astutil.Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ast.SelectorExpr:
+			return false
+		case *ast.FuncType:
+			// param names aren't part of a func type, so they're dropped to let func(id int) and
+			// func(int) compare equal
+			n.Params.List = unnamedFields(n.Params.List)
+			if n.Results != nil {
+				n.Results.List = unnamedFields(n.Results.List)
+			}
+		case *ast.Ident:
+			if _, ok := c.Parent().(*ast.Field); ok && c.Name() == "Names" {
+				return false
+			}
+			if types.Universe.Lookup(n.Name) != nil || n.Name == "_" {
+				return false
+			}
+			if placeholder, ok := typeParams[n.Name]; ok {
+				c.Replace(ast.NewIdent(placeholder))
+				return false
+			}
+			c.Replace(&ast.SelectorExpr{X: ast.NewIdent(pkgName), Sel: ast.NewIdent(n.Name)})
+			qualified = true
+			return false
+		}
+		return true
+	}, nil)
+	return result.(ast.Expr), qualified
+}
+
+// unnamedFields returns a field per name of the params or results, without the names.
+func unnamedFields(fields []*ast.Field) []*ast.Field {
+	var unnamed []*ast.Field
+	for _, field := range fields {
+		unnamed = append(unnamed, &ast.Field{Type: field.Type})
+		for range len(field.Names) - 1 {
+			unnamed = append(unnamed, &ast.Field{Type: cloneExpr(field.Type)})
+		}
+	}
+	return unnamed
+}
+
+func cloneExpr(expression ast.Expr) ast.Expr {
+	var buf strings.Builder
+	if err := printer.Fprint(&buf, token.NewFileSet(), expression); err != nil {
+		return expression
+	}
+	clone, err := parser.ParseExpr(buf.String())
+	if err != nil {
+		return expression
+	}
+	return clone
+}
+
+func embeddedFieldName(expression ast.Expr) string {
+	switch e := expression.(type) {
+	case *ast.StarExpr:
+		return embeddedFieldName(e.X)
+	case *ast.IndexExpr:
+		return embeddedFieldName(e.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
 }
 
 func pkgAliasVisitor(expression ast.Expr) ([]string, error) {
@@ -716,8 +3139,22 @@ func pkgAliasVisitor(expression ast.Expr) ([]string, error) {
 			visit(v.Sel)
 		case *ast.StarExpr:
 			visit(v.X)
+		case *ast.ParenExpr:
+			visit(v.X)
 		case *ast.ArrayType:
 			visit(v.Elt)
+		case *ast.Ellipsis:
+			visit(v.Elt)
+		case *ast.ChanType:
+			visit(v.Value)
+		case *ast.IndexExpr:
+			visit(v.X)
+			visit(v.Index)
+		case *ast.IndexListExpr:
+			visit(v.X)
+			for _, index := range v.Indices {
+				visit(index)
+			}
 		case *ast.MapType:
 			visit(v.Key)
 			visit(v.Value)
@@ -742,6 +3179,141 @@ func pkgAliasVisitor(expression ast.Expr) ([]string, error) {
 	return pkgAliases, nil
 }
 
+func validateConversionTemplates(conversions []Conversion) error {
+	var errs []error
+	for _, conversion := range conversions {
+		if err := conversion.validateFieldTag(); err != nil {
+			errs = append(errs, fmt.Errorf("conversion %s → %s: %w", conversion.SourceType, conversion.DestType, err))
+		}
+		if err := conversion.validateTypePatterns(); err != nil {
+			errs = append(errs, fmt.Errorf("conversion %s → %s: %w", conversion.SourceType+conversion.SourceTypePattern, conversion.DestType+conversion.DestTypePattern, err))
+			continue
+		}
+		if err := conversion.validateSrcStruct(); err != nil {
+			errs = append(errs, fmt.Errorf("conversion %s → %s: %w", conversion.SourceType, conversion.DestType, err))
+		}
+		if err := conversion.validateEnum(); err != nil {
+			errs = append(errs, fmt.Errorf("conversion %s → %s: %w", conversion.SourceType, conversion.DestType, err))
+			continue
+		}
+		conversion = conversion.expandEnum()
+		if _, err := template.New("conversion").Parse(conversion.Conversion.Tmpl); err != nil {
+			errs = append(errs, fmt.Errorf("conversion %s → %s: invalid conversion template: %w", conversion.SourceType, conversion.DestType, err))
+		}
+		if _, err := template.New("reverse_conversion").Parse(conversion.ReverseConversion.Tmpl); err != nil {
+			errs = append(errs, fmt.Errorf("conversion %s → %s: invalid reverse_conversion template: %w", conversion.SourceType, conversion.DestType, err))
+		}
+		if err := conversion.Conversion.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("conversion %s → %s: conversion: %w", conversion.SourceType, conversion.DestType, err))
+		}
+		if err := conversion.ReverseConversion.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("conversion %s → %s: reverse_conversion: %w", conversion.SourceType, conversion.DestType, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateSrcStruct keeps {{ .SrcStruct }} to conversions that opt into reading the whole
+// source struct, so the single-field contract stays visible in the config.
+func (c Conversion) validateSrcStruct() error {
+	if c.NeedsSrcStruct {
+		return nil
+	}
+	for _, tmpl := range []string{c.Conversion.Tmpl, c.Conversion.Default, c.ReverseConversion.Tmpl, c.ReverseConversion.Default} {
+		if srcStructPattern.MatchString(tmpl) {
+			return fmt.Errorf("templates using {{ .SrcStruct }} need needs_src_struct: true")
+		}
+	}
+	return nil
+}
+
+func validateAdditionalArgs(additionalArgs []AdditionalArg, reserved []string) error {
+	seen := map[string]struct{}{}
+	for _, arg := range additionalArgs {
+		for _, name := range reserved {
+			if arg.Name == name {
+				return fmt.Errorf("additional arg %q conflicts with the reserved identifier %q", arg.Name, name)
+			}
+		}
+		if reservedTemplateKeyPattern.MatchString(arg.Name) {
+			return fmt.Errorf("additional arg %q conflicts with the template variable {{ .%s }}", arg.Name, arg.Name)
+		}
+		if _, exists := seen[arg.Name]; exists {
+			return fmt.Errorf("duplicate additional arg %q", arg.Name)
+		}
+		if arg.MatchFields && arg.DestField != "" {
+			return fmt.Errorf("additional arg %q: match_fields and dest_field can't be combined", arg.Name)
+		}
+		seen[arg.Name] = struct{}{}
+	}
+	return nil
+}
+
+func typeIdentifier(typ string) string {
+	parts := strings.FieldsFunc(typ, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for idx := 1; idx < len(parts); idx++ {
+		parts[idx] = strings.ToUpper(parts[idx][:1]) + parts[idx][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+func isNillableType(typ string) bool {
+	return strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[")
+}
+
+func isEmptyInterface(typ string) bool {
+	return typ == "any" || typ == "interface{}"
+}
+
+func findCompositeMapping(customFieldMappings []CustomFieldMapping, dest FieldDefinition) *CustomFieldMapping {
+	for _, customFieldMapping := range customFieldMappings {
+		if customFieldMapping.DestField == dest.Name && len(customFieldMapping.SourceFields) > 0 {
+			return &customFieldMapping
+		}
+	}
+	return nil
+}
+
+func findSplitMapping(customFieldMappings []CustomFieldMapping, dest FieldDefinition) (*CustomFieldMapping, int) {
+	for _, customFieldMapping := range customFieldMappings {
+		for idx, destField := range customFieldMapping.DestFields {
+			if destField == dest.Name {
+				return &customFieldMapping, idx
+			}
+		}
+	}
+	return nil, -1
+}
+
+func validateSplitMappings(customFieldMappings []CustomFieldMapping, byName map[string]FieldDefinition, destFields []FieldDefinition, mapping Mapping) error {
+	for _, customFieldMapping := range customFieldMappings {
+		if len(customFieldMapping.DestFields) == 0 {
+			continue
+		}
+		if len(customFieldMapping.Tmpls) != len(customFieldMapping.DestFields) {
+			return fmt.Errorf("custom field mapping for %s: expected %d tmpls for dest fields %v, got %d", customFieldMapping.SourceField, len(customFieldMapping.DestFields), customFieldMapping.DestFields, len(customFieldMapping.Tmpls))
+		}
+		if _, ok := byName[customFieldMapping.SourceField]; !ok {
+			return fmt.Errorf("custom field mapping for %v: source field %s not found in %s", customFieldMapping.DestFields, customFieldMapping.SourceField, mapping.primary().GetUnaliasedType())
+		}
+		for _, name := range customFieldMapping.DestFields {
+			found := false
+			for _, destField := range destFields {
+				if destField.Name == name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("custom field mapping for %s: dest field %s not found in %s", customFieldMapping.SourceField, name, mapping.To.GetUnaliasedType())
+			}
+		}
+	}
+	return nil
+}
+
 func findAdditionalArg(additionalArgs []AdditionalArg, dest FieldDefinition) *AdditionalArg {
 	for _, arg := range additionalArgs {
 		if arg.DestField == dest.Name {
@@ -751,46 +3323,86 @@ func findAdditionalArg(additionalArgs []AdditionalArg, dest FieldDefinition) *Ad
 	return nil
 }
 
-func tagValue(tag string, key string) string {
+// structTag is the value of one key of a struct tag, split into the name used for matching
+// and the options following it, e.g. omitempty in json:"name,omitempty".
+type structTag struct {
+	name    string
+	options []string
+}
+
+func parseTag(tag string, key string) structTag {
 	if tag == "" {
-		return ""
+		return structTag{}
 	}
-	st := reflect.StructTag(tag)
-	v := st.Get(key)
+	v := reflect.StructTag(tag).Get(key)
 	if v == "" {
-		return ""
+		return structTag{}
 	}
 	parts := strings.Split(v, ",")
 	if parts[0] == "-" {
-		return ""
+		return structTag{}
+	}
+	return structTag{name: parts[0], options: parts[1:]}
+}
+
+func (t structTag) hasOption(option string) bool {
+	return slices.Contains(t.options, option)
+}
+
+func tagValue(tag string, key string) string {
+	return parseTag(tag, key).name
+}
+
+// tagOmitsEmpty reports whether the tag has an omitempty or omitzero option for any of the keys.
+func tagOmitsEmpty(tag string, keys []string) bool {
+	return slices.ContainsFunc(keys, func(key string) bool {
+		parsed := parseTag(tag, key)
+		return parsed.hasOption("omitempty") || parsed.hasOption("omitzero")
+	})
+}
+
+func hasSkipTag(tag string, keys []string) bool {
+	if tag == "" {
+		return false
 	}
-	return parts[0]
+	st := reflect.StructTag(tag)
+	for _, key := range keys {
+		if st.Get(key) == "-" {
+			return true
+		}
+	}
+	return false
 }
 
 func findSourceForDest(
 	dest FieldDefinition,
-	byName, byTag map[string]FieldDefinition,
+	byName map[string]FieldDefinition,
+	byTag map[string]map[string]FieldDefinition,
 	customFieldMappings []CustomFieldMapping,
-	tag string,
+	tags []string,
 	sourceFields []FieldDefinition,
-) *FieldDefinition {
+	matchers []FieldMatcher,
+) (*FieldDefinition, MatchKind, *CustomFieldMapping) {
 	for _, customFieldMapping := range customFieldMappings {
-		if customFieldMapping.DestField != "" && customFieldMapping.DestField == dest.Name && customFieldMapping.SourceField != "" {
+		if customFieldMapping.DestField != "" && customFieldMapping.DestField == dest.Name && customFieldMapping.SourceField != "" && customFieldMapping.SourceIndex == 0 {
 			if field, ok := byName[customFieldMapping.SourceField]; ok {
-				return &field
+				return &field, MatchKindCustom, &customFieldMapping
 			}
 		}
-		if customFieldMapping.DestTag != "" {
-			customTag := customFieldMapping.Tag
-			if customTag == "" {
-				customTag = tag
+		if customFieldMapping.DestTag != "" && customFieldMapping.SourceTag != "" {
+			// without an explicit tag key every match tag is tried in order, so a field
+			// whose first key is missing or has an empty name falls back to the next one
+			customTags := tags
+			if customFieldMapping.Tag != "" {
+				customTags = []string{customFieldMapping.Tag}
 			}
-			if tagVal := tagValue(dest.Tag, customTag); tagVal != "" && tagVal == customFieldMapping.DestTag {
-				if customFieldMapping.SourceTag != "" {
-					for _, field := range sourceFields {
-						if tagValue(field.Tag, customTag) == customFieldMapping.SourceTag {
-							return &field
-						}
+			for _, customTag := range customTags {
+				if tagValue(dest.Tag, customTag) != customFieldMapping.DestTag {
+					continue
+				}
+				for _, field := range sourceFields {
+					if tagValue(field.Tag, customTag) == customFieldMapping.SourceTag {
+						return &field, MatchKindCustom, &customFieldMapping
 					}
 				}
 			}
@@ -798,12 +3410,17 @@ func findSourceForDest(
 	}
 
 	if field, ok := byName[dest.Name]; ok {
-		return &field
+		return &field, MatchKindName, nil
 	}
-	if tagVal := tagValue(dest.Tag, tag); tagVal != "" {
-		if field, ok := byTag[tagVal]; ok {
-			return &field
+	for _, tag := range tags {
+		if tagVal := tagValue(dest.Tag, tag); tagVal != "" {
+			if field, ok := byTag[tag][tagVal]; ok {
+				return &field, MatchKindTag, nil
+			}
 		}
 	}
-	return nil
+	if field, ok := matchWithMatchers(matchers, dest, sourceFields, byName); ok {
+		return field, MatchKindMatcher, nil
+	}
+	return nil, MatchKindUnmapped, nil
 }
@@ -0,0 +1,1130 @@
+package generator
+
+import (
+	"fmt"
+	"go/parser"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// testdata is the import path of the fixture packages in testdata, which are loaded like any
+// other package of the module, but skipped by ./... patterns.
+const testdata = "github.com/dkowalsky92/structmap/internal/generator/testdata"
+
+// testConfig parses a YAML config, in which "$testdata" expands to the import path of the fixtures.
+func testConfig(tb testing.TB, config string) Config {
+	tb.Helper()
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(os.Expand(config, func(string) string { return testdata })), &cfg); err != nil {
+		tb.Fatalf("invalid test config: %v", err)
+	}
+	if cfg.OutPackageName == "" {
+		cfg.OutPackageName = "mapping"
+	}
+	return cfg
+}
+
+func generateConfig(t *testing.T, config string) (string, Report, error) {
+	t.Helper()
+	return NewGenerator(testConfig(t, config), Conversions{}).GenerateWithReport()
+}
+
+func mustGenerate(t *testing.T, config string) (string, Report) {
+	t.Helper()
+	code, report, err := generateConfig(t, config)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	return code, report
+}
+
+// compile builds the generated code as a package of the module, so it may import the fixtures.
+func compile(t *testing.T, code string) {
+	t.Helper()
+	goCommand(t, []File{{Name: "structmap.gen.go", Code: code}}, "vet")
+}
+
+// goCommand writes files into a temporary package of the module and runs the go subcommand on it.
+func goCommand(t *testing.T, files []File, subcommand string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the generated code with the go command")
+	}
+	dir, err := os.MkdirTemp("testdata", "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	var code strings.Builder
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, file.Name), []byte(file.Code), 0644); err != nil {
+			t.Fatal(err)
+		}
+		code.WriteString(file.Code)
+	}
+	if out, err := exec.Command("go", subcommand, "./"+dir).CombinedOutput(); err != nil {
+		t.Fatalf("go %s failed: %v\n%s\n%s", subcommand, err, out, code.String())
+	}
+}
+
+func TestNewFieldDefinitionQualifiers(t *testing.T) {
+	alias := "tm"
+	tests := []struct {
+		name        string
+		typeStr     string
+		importInfos []ImportInfo
+		want        string
+	}{
+		{
+			name:        "package name",
+			typeStr:     "time.Time",
+			importInfos: []ImportInfo{NewImportInfo(nil, "time", "time")},
+			want:        "{{ .Import0 }}.Time",
+		},
+		{
+			name:        "alias",
+			typeStr:     "map[string]*tm.Duration",
+			importInfos: []ImportInfo{NewImportInfo(&alias, "time", "time")},
+			want:        "map[string]*{{ .Import0 }}.Duration",
+		},
+		{
+			name:        "identifier ending in the package name",
+			typeStr:     "func(mytime.Time, time.Time) xtime.Time",
+			importInfos: []ImportInfo{NewImportInfo(nil, "time", "time")},
+			want:        "func(mytime.Time, {{ .Import0 }}.Time) xtime.Time",
+		},
+		{
+			name:    "several imports",
+			typeStr: "map[uuid.UUID][]time.Time",
+			importInfos: []ImportInfo{
+				NewImportInfo(nil, "time", "time"),
+				NewImportInfo(nil, "uuid", "github.com/google/uuid"),
+			},
+			want: "map[{{ .Import1 }}.UUID][]{{ .Import0 }}.Time",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := NewFieldDefinition("Field", tt.typeStr, "", tt.importInfos)
+			if field.TypeTemplate != tt.want {
+				t.Errorf("NewFieldDefinition() type = %q, want %q", field.TypeTemplate, tt.want)
+			}
+			if len(field.Imports) != len(tt.importInfos) {
+				t.Errorf("NewFieldDefinition() imports = %v, want %d", field.Imports, len(tt.importInfos))
+			}
+		})
+	}
+}
+
+func BenchmarkGenerate50Fields(b *testing.B) {
+	const mapping = `
+mappings:
+  - from: {type: "{{ .Import0 }}.Record", imports: [$testdata/wide]}
+    to: {type: "{{ .Import0 }}.RecordDTO", imports: [$testdata/wide]}
+`
+	var conversions Conversions
+	basic := []string{"int8", "int16", "int32", "uint16", "uint32", "float32", "complex64", "rune"}
+	for _, source := range basic {
+		for _, dest := range basic {
+			if source != dest {
+				conversions.Conversions = append(conversions.Conversions, Conversion{
+					SourceType: source,
+					DestType:   dest,
+					Conversion: ConversionTemplate{Tmpl: fmt.Sprintf("{{ .Dest }} = %s({{ .Source }})", dest)},
+				})
+			}
+		}
+	}
+	// packages are loaded once, so the benchmark measures generation
+	loaded := NewGenerator(Config{}, Conversions{})
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{name: "fast path", config: mapping},
+		{
+			// an apply_to_same_type conversion makes every field go through the full lookup
+			name: "without fast path",
+			config: mapping + `    custom_conversions:
+      - source_type: complex128
+        dest_type: complex128
+        apply_to_same_type: true
+        conversion: {tmpl: "{{ .Dest }} = {{ .Source }}"}
+`,
+		},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			config := testConfig(b, tt.config)
+			for b.Loop() {
+				g := NewGenerator(config, conversions)
+				g.SetPackageLoader(loaded.packageManager.GetPackage)
+				if _, err := g.Generate(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSameTypeConversions(t *testing.T) {
+	const mapping = `
+mappings:
+  - from: {type: "{{ .Import0 }}.Profile", imports: [$testdata/profiles]}
+    to: {type: "{{ .Import0 }}.ProfileDTO", imports: [$testdata/profiles]}
+`
+	tests := []struct {
+		name        string
+		conversions string
+		want        []string
+		wantNot     []string
+	}{
+		{
+			name:    "no conversions",
+			want:    []string{"dst.Tags = src.Tags", "dst.Rank = src.Rank"},
+			wantNot: []string{"append("},
+		},
+		{
+			name: "declared between identical types",
+			conversions: `
+  - source_type: "[]string"
+    dest_type: "[]string"
+    conversion: {tmpl: "{{ .Dest }} = append([]string(nil), {{ .Source }}...)"}
+`,
+			want:    []string{"dst.Tags = append([]string(nil), src.Tags...)", "dst.Scores = src.Scores"},
+			wantNot: []string{"dst.Tags = src.Tags"},
+		},
+		{
+			name: "type pattern without apply_to_same_type",
+			conversions: `
+  - source_type_pattern: '\[\](\w+)'
+    dest_type_pattern: '\[\](\w+)'
+    conversion: {tmpl: "{{ .Dest }} = append([]{{ .SourceMatch1 }}(nil), {{ .Source }}...)"}
+`,
+			want: []string{"dst.Tags = src.Tags", "dst.Scores = src.Scores"},
+		},
+		{
+			name: "type pattern with apply_to_same_type",
+			conversions: `
+  - source_type_pattern: '\[\](\w+)'
+    dest_type_pattern: '\[\](\w+)'
+    apply_to_same_type: true
+    conversion: {tmpl: "{{ .Dest }} = append([]{{ .SourceMatch1 }}(nil), {{ .Source }}...)"}
+`,
+			want: []string{"dst.Tags = append([]string(nil), src.Tags...)", "dst.Scores = append([]int(nil), src.Scores...)"},
+		},
+		{
+			name: "underlying type without apply_to_same_type",
+			conversions: `
+  - source_type: int64
+    dest_type: int64
+    match_underlying: true
+    conversion: {tmpl: "{{ .Dest }} = {{ .Source }} * 2"}
+`,
+			want: []string{"dst.Rank = src.Rank"},
+		},
+		{
+			name: "underlying type with apply_to_same_type",
+			conversions: `
+  - source_type: int64
+    dest_type: int64
+    match_underlying: true
+    apply_to_same_type: true
+    conversion: {tmpl: "{{ .Dest }} = {{ .Source }} * 2"}
+`,
+			want: []string{"* 2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := mapping
+			if tt.conversions != "" {
+				config += "conversions:" + tt.conversions
+			}
+			code, _ := mustGenerate(t, config)
+			for _, want := range tt.want {
+				if !strings.Contains(code, want) {
+					t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+				}
+			}
+			for _, wantNot := range tt.wantNot {
+				if strings.Contains(code, wantNot) {
+					t.Errorf("generated code contains %q:\n%s", wantNot, code)
+				}
+			}
+			compile(t, code)
+		})
+	}
+}
+
+func TestFromConcreteImplements(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		concrete string
+		wantErr  string
+	}{
+		{name: "pointer receiver", from: "Shape", concrete: "*{{ .Import0 }}.Square"},
+		{name: "value receiver", from: "Shape", concrete: "{{ .Import0 }}.Circle"},
+		{name: "pointer to value receiver", from: "Shape", concrete: "*{{ .Import0 }}.Circle"},
+		{
+			name:     "pointer receiver on value",
+			from:     "Shape",
+			concrete: "{{ .Import0 }}.Square",
+			wantErr:  "mapping shapes.Shape → shapes.ShapeDTO: from_concrete shapes.Square doesn't implement shapes.Shape",
+		},
+		{
+			name:     "no methods",
+			from:     "Shape",
+			concrete: "*{{ .Import0 }}.Label",
+			wantErr:  "from_concrete *shapes.Label doesn't implement shapes.Shape",
+		},
+		{
+			name:     "from is a struct",
+			from:     "Label",
+			concrete: "*{{ .Import0 }}.Square",
+			wantErr:  "from_concrete needs from to be an interface, shapes.Label isn't one",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, err := generateConfig(t, fmt.Sprintf(`
+mappings:
+  - from: {type: "{{ .Import0 }}.%s", imports: [$testdata/shapes]}
+    from_concrete: {type: "%s", imports: [$testdata/shapes]}
+    to: {type: "{{ .Import0 }}.ShapeDTO", imports: [$testdata/shapes]}
+`, tt.from, tt.concrete))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Generate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			compile(t, code)
+		})
+	}
+}
+
+func TestShadowPromotedFields(t *testing.T) {
+	tests := []struct {
+		typeName string
+		want     []string
+	}{
+		// Meta.Note shadows the Note promoted from Audit
+		{typeName: "Meta", want: []string{"CreatedAt", "Note", "Version"}},
+		// Account.Note shadows Meta.Note, Version is promoted from Meta and Flags at the same depth
+		{typeName: "Account", want: []string{"CreatedAt", "Active", "ID", "Note"}},
+		{typeName: "Shared", want: []string{"CreatedAt", "Note", "Active"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			g := NewGenerator(Config{}, Conversions{})
+			fields, err := g.extractFieldsFromPackage(testdata+"/accounts", tt.typeName, nil)
+			if err != nil {
+				t.Fatalf("extractFieldsFromPackage() error = %v", err)
+			}
+			var names []string
+			for _, field := range fields {
+				names = append(names, field.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("fields = %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	t.Run("mapping", func(t *testing.T) {
+		code, report := mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Account", imports: [$testdata/accounts]}
+    to: {type: "{{ .Import0 }}.AccountDTO", imports: [$testdata/accounts]}
+`)
+		if strings.Contains(code, "src.Version") {
+			t.Errorf("generated code selects the ambiguous Version:\n%s", code)
+		}
+		for _, field := range report.Mappings[0].Fields {
+			if field.DestField == "Version" && field.MatchedBy != MatchKindUnmapped {
+				t.Errorf("Version matched by %s, want it unmapped", field.MatchedBy)
+			}
+		}
+		compile(t, code)
+	})
+}
+
+func TestOmitEmptyConditions(t *testing.T) {
+	code, _ := mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Event", imports: [$testdata/events]}
+    to: {type: "{{ .Import0 }}.EventPatch", imports: [$testdata/events]}
+    in_place: true
+    custom_field_mappings:
+      - {source_field: Name, dest_field: Name, omit_empty: true}
+      - {source_field: Location, dest_field: Location, omit_empty: true}
+      - {source_field: Window, dest_field: Window, omit_empty: true}
+      - {source_field: Grid, dest_field: Grid, omit_empty: true}
+      - {source_field: Slots, dest_field: Slots, omit_empty: true}
+      - {source_field: Handlers, dest_field: Handlers, omit_empty: true}
+`)
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{field: "Name", want: `if src.Name != "" {`},
+		{field: "Location", want: "if src.Location != (ref1.Point{}) {"},
+		{field: "Window", want: "if !reflect.ValueOf(src.Window).IsZero() {"},
+		{field: "Grid", want: "if src.Grid != ([2][2]int{}) {"},
+		{field: "Slots", want: "if !reflect.ValueOf(src.Slots).IsZero() {"},
+		{field: "Handlers", want: "if !reflect.ValueOf(src.Handlers).IsZero() {"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if !strings.Contains(code, tt.want) {
+				t.Errorf("generated code doesn't contain %q:\n%s", tt.want, code)
+			}
+		})
+	}
+	compile(t, code)
+}
+
+func TestInterfaceFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		to          string
+		conversions string
+		want        []string
+		wantErr     string
+	}{
+		{
+			name: "same interfaces",
+			to:   "FileDTO",
+			want: []string{"dst.Body = src.Body", "dst.Err = src.Err", "dst.Meta = src.Meta"},
+		},
+		{
+			name:    "different interfaces",
+			to:      "UploadDTO",
+			wantErr: "unsupported assignment of interface field Body from",
+		},
+		{
+			name: "different interfaces with a conversion",
+			to:   "UploadDTO",
+			conversions: `
+conversions:
+  - source_type: "{{ .Import0 }}.Reader"
+    dest_type: "{{ .Import0 }}.ReadCloser"
+    imports: [io]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.NopCloser({{ .Source }})"
+`,
+			want: []string{".NopCloser(src.Body)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, err := generateConfig(t, fmt.Sprintf(`
+mappings:
+  - from: {type: "{{ .Import0 }}.File", imports: [$testdata/files]}
+    to: {type: "{{ .Import0 }}.%s", imports: [$testdata/files]}
+%s`, tt.to, tt.conversions))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Generate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(code, want) {
+					t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+				}
+			}
+			compile(t, code)
+		})
+	}
+}
+
+func TestEmbeddedInterfaces(t *testing.T) {
+	g := NewGenerator(Config{}, Conversions{})
+	fields, err := g.extractFieldsFromPackage(testdata+"/labels", "Tag", nil)
+	if err != nil {
+		t.Fatalf("extractFieldsFromPackage() error = %v", err)
+	}
+	var names []string
+	for _, field := range fields {
+		if field.Kind != FieldKindInterface && field.Name != "Key" {
+			t.Errorf("field %s kind = %s, want %s", field.Name, field.Kind, FieldKindInterface)
+		}
+		names = append(names, field.Name)
+	}
+	if want := []string{"Stringer", "Named", "Key"}; !slices.Equal(names, want) {
+		t.Errorf("fields = %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		to   string
+		want []string
+	}{
+		{to: "TagDTO", want: []string{"dst.Stringer = src.Stringer", "dst.Named = src.Named"}},
+		{to: "TagView", want: []string{"dst.Stringer = src.Stringer", "dst.Key = src.Key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			code, _ := mustGenerate(t, fmt.Sprintf(`
+mappings:
+  - from: {type: "{{ .Import0 }}.Tag", imports: [$testdata/labels]}
+    to: {type: "{{ .Import0 }}.%s", imports: [$testdata/labels]}
+`, tt.to))
+			for _, want := range tt.want {
+				if !strings.Contains(code, want) {
+					t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+				}
+			}
+			compile(t, code)
+		})
+	}
+}
+
+func TestTypeAliases(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{name: "alias", from: "{{ .Import0 }}.Profile", to: "{{ .Import1 }}.ProfileDTO"},
+		{name: "defined type", from: "{{ .Import0 }}.Member", to: "{{ .Import1 }}.ProfileDTO"},
+		{name: "alias of a defined type of a defined type", from: "{{ .Import0 }}.Member", to: "{{ .Import0 }}.Latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, report := mustGenerate(t, fmt.Sprintf(`
+mappings:
+  - from: {type: "%s", imports: [$testdata/aliases, $testdata/profiles]}
+    to: {type: "%s", imports: [$testdata/aliases, $testdata/profiles]}
+`, tt.from, tt.to))
+			var mapped []string
+			for _, field := range report.Mappings[0].Fields {
+				if field.MatchedBy == MatchKindName {
+					mapped = append(mapped, field.DestField)
+				}
+			}
+			if want := []string{"Nick", "Tags", "Scores", "Rank"}; !slices.Equal(mapped, want) {
+				t.Errorf("fields matched by name = %v, want %v:\n%s", mapped, want, code)
+			}
+			compile(t, code)
+		})
+	}
+}
+
+func TestPkgAliasVisitor(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []string
+	}{
+		{expression: "container.List[pkg.Item]", want: []string{"container", "pkg"}},
+		{expression: "chan pkg.Event", want: []string{"pkg"}},
+		{expression: "<-chan []*pkg.Event", want: []string{"pkg"}},
+		{expression: "cache.Map[uuid.UUID, *pkg.Item]", want: []string{"cache", "uuid", "pkg"}},
+		{expression: "map[pkg.Key]container.List[pkg.Item]", want: []string{"pkg", "container"}},
+		{expression: "func(context.Context, ...pkg.Item) (chan<- time.Time, error)", want: []string{"context", "pkg", "time"}},
+		{expression: "List[int]", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := parser.ParseExpr(tt.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := pkgAliasVisitor(expression)
+			if err != nil {
+				t.Fatalf("pkgAliasVisitor() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pkgAliasVisitor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("mapping", func(t *testing.T) {
+		code, _ := mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Batch", imports: [$testdata/jobs]}
+    to: {type: "{{ .Import0 }}.BatchDTO", imports: [$testdata/jobs]}
+`)
+		for _, want := range []string{"dst.Pending = src.Pending", "dst.Index = src.Index"} {
+			if !strings.Contains(code, want) {
+				t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+			}
+		}
+		compile(t, code)
+	})
+}
+
+func TestTemplatesNotEscaped(t *testing.T) {
+	code, _ := mustGenerate(t, `
+conversions:
+  - source_type: int
+    dest_type: int64
+    conversion:
+      tmpl: "if {{ .Source }} > 0 && {{ .Source }} < 1<<16 { {{ .Dest }} = int64({{ .Source }}) }"
+  - source_type: int64
+    dest_type: string
+    imports: [fmt]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.Sprintf(\"<%d> & 'id'\", {{ .Source }})"
+mappings:
+  - from: {type: "{{ .Import0 }}.Order", imports: [$testdata/orders]}
+    to: {type: "{{ .Import0 }}.OrderDTO", imports: [$testdata/orders]}
+    custom_field_mappings:
+      - source_field: Discount
+        dest_field: Discount
+        conversion:
+          tmpl: "if {{ .Source }} != nil && *{{ .Source }} >= 0 { {{ .Dest }} = \"<\" + {{ .Import0 }}.Itoa(*{{ .Source }}) + \"%>\" }"
+          imports: [strconv]
+`)
+	for _, want := range []string{
+		"if src.Quantity > 0 && src.Quantity < 1<<16 {",
+		`Sprintf("<%d> & 'id'", src.ID)`,
+		`if src.Discount != nil && *src.Discount >= 0 {`,
+		`.Itoa(*src.Discount) + "%>"`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+		}
+	}
+	compile(t, code)
+}
+
+func TestSplitTypeArgs(t *testing.T) {
+	imports := []string{"example.com/items", "example.com/boxes", "time"}
+	tests := []struct {
+		typeTemplate string
+		wantPkgPath  string
+		wantName     string
+		wantArgs     []string
+		wantErr      bool
+	}{
+		{typeTemplate: "{{ .Import1 }}.Box", wantPkgPath: "example.com/boxes", wantName: "Box"},
+		{
+			typeTemplate: "{{ .Import1 }}.Box[{{ .Import0 }}.Item]",
+			wantPkgPath:  "example.com/boxes",
+			wantName:     "Box",
+			wantArgs:     []string{"{{ .Import0 }}.Item"},
+		},
+		{
+			typeTemplate: "{{ .Import1 }}.Pair[*{{ .Import0 }}.Item, map[string]{{ .Import2 }}.Time]",
+			wantPkgPath:  "example.com/boxes",
+			wantName:     "Pair",
+			wantArgs:     []string{"*{{ .Import0 }}.Item", "map[string]{{ .Import2 }}.Time"},
+		},
+		{
+			typeTemplate: "{{ .Import1 }}.Box[{{ .Import1 }}.Box[{{ .Import0 }}.Item]]",
+			wantPkgPath:  "example.com/boxes",
+			wantName:     "Box",
+			wantArgs:     []string{"{{ .Import1 }}.Box[{{ .Import0 }}.Item]"},
+		},
+		{typeTemplate: "Box[int]", wantPkgPath: "example.com/items", wantName: "Box", wantArgs: []string{"int"}},
+		{typeTemplate: "{{ .Import3 }}.Box", wantErr: true},
+		{typeTemplate: "[]{{ .Import1 }}.Box", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeTemplate, func(t *testing.T) {
+			pkgPath, name, typeArgs, err := NewTypeWithImportsTemplate(tt.typeTemplate, imports).SplitTypeArgs()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("SplitTypeArgs() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitTypeArgs() error = %v", err)
+			}
+			var args []string
+			for _, typeArg := range typeArgs {
+				args = append(args, typeArg.TypeTemplate)
+			}
+			if pkgPath != tt.wantPkgPath || name != tt.wantName || !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("SplitTypeArgs() = %s, %s, %q, want %s, %s, %q", pkgPath, name, args, tt.wantPkgPath, tt.wantName, tt.wantArgs)
+			}
+		})
+	}
+
+	t.Run("mapping", func(t *testing.T) {
+		code, _ := mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import1 }}.Box[{{ .Import0 }}.Order]", imports: [$testdata/orders, $testdata/boxes]}
+    to: {type: "{{ .Import1 }}.BoxDTO[{{ .Import0 }}.Order]", imports: [$testdata/orders, $testdata/boxes]}
+`)
+		for _, want := range []string{"func MapBoxOrderToBoxDTOOrder(", "dst.Value = src.Value", "dst.Items = src.Items"} {
+			if !strings.Contains(code, want) {
+				t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+			}
+		}
+		compile(t, code)
+	})
+}
+
+func TestCustomFieldMappingTagKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		fieldConfig string
+		want        string
+	}{
+		{name: "first key present", fieldConfig: "{source_tag: first, dest_tag: a}", want: "dst.A = src.First"},
+		{name: "first key with an empty name", fieldConfig: "{source_tag: col_b, dest_tag: b}", want: "dst.B = src.Second"},
+		{name: "first key missing", fieldConfig: "{source_tag: col_c, dest_tag: c}", want: "dst.C = src.Third"},
+		{name: "explicit key", fieldConfig: "{source_tag: col_d, dest_tag: d, tag: db}", want: "dst.D = src.Fourth"},
+		{name: "explicit key without a match", fieldConfig: "{source_tag: col_d, dest_tag: d, tag: json}", want: "no matching source found for field: D"},
+		{name: "value of another key", fieldConfig: "{source_tag: fourth, dest_tag: d, tag: db}", want: "no matching source found for field: D"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := mustGenerate(t, fmt.Sprintf(`
+mappings:
+  - from: {type: "{{ .Import0 }}.Row", imports: [$testdata/rows]}
+    to: {type: "{{ .Import0 }}.RowDTO", imports: [$testdata/rows]}
+    tags: [json, db]
+    custom_field_mappings: [%s]
+`, tt.fieldConfig))
+			if !strings.Contains(code, tt.want) {
+				t.Errorf("generated code doesn't contain %q:\n%s", tt.want, code)
+			}
+			compile(t, code)
+		})
+	}
+}
+
+func TestAliasPrefix(t *testing.T) {
+	const mappings = `
+mappings:
+  - from: {type: "{{ .Import0 }}.Order", imports: [$testdata/orders]}
+    to: {type: "{{ .Import0 }}.OrderDTO", imports: [$testdata/orders]}
+  - from: {type: "{{ .Import0 }}.Shape", imports: [$testdata/shapes]}
+    from_concrete: {type: "*{{ .Import0 }}.Square", imports: [$testdata/shapes]}
+    to: {type: "{{ .Import0 }}.ShapeDTO", imports: [$testdata/shapes]}
+conversions:
+  - source_type: int64
+    dest_type: string
+    imports: [strconv]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.FormatInt({{ .Source }}, 10)"
+  - source_type: "*int"
+    dest_type: string
+    imports: [fmt]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.Sprint({{ .Source }})"
+generate_dispatcher: true
+`
+	tests := []struct {
+		prefix  string
+		wantErr bool
+	}{
+		{prefix: "sm"},
+		{prefix: "ref_"},
+		{prefix: "Pkg"},
+		{prefix: "1x", wantErr: true},
+		{prefix: "_", wantErr: true},
+		{prefix: "a-b", wantErr: true},
+	}
+	refAlias := regexp.MustCompile(`\bref\d`)
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			config := testConfig(t, mappings)
+			config.AliasPrefix = tt.prefix
+			config.GenerateTests = true
+			files, _, err := NewGenerator(config, Conversions{}).GenerateFiles()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid alias_prefix") {
+					t.Fatalf("GenerateFiles() error = %v, want an invalid alias_prefix error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateFiles() error = %v", err)
+			}
+			for _, file := range files {
+				if tt.prefix != "ref_" && refAlias.MatchString(file.Code) {
+					t.Errorf("%s uses a ref alias:\n%s", file.Name, file.Code)
+				}
+				if !strings.Contains(file.Code, tt.prefix+"1.") {
+					t.Errorf("%s doesn't use the %s1 alias:\n%s", file.Name, tt.prefix, file.Code)
+				}
+			}
+			goCommand(t, files, "test")
+		})
+	}
+}
+
+func TestSliceElementPointers(t *testing.T) {
+	code, report := mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Catalog", imports: [$testdata/catalog]}
+    to: {type: "{{ .Import0 }}.CatalogDTO", imports: [$testdata/catalog]}
+conversions:
+  - source_type: "{{ .Import0 }}.Item"
+    dest_type: "{{ .Import0 }}.ItemDTO"
+    imports: [$testdata/catalog, strconv]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.ItemDTO{ID: {{ .Import1 }}.FormatInt({{ .Source }}.ID, 10)}"
+`)
+	for _, field := range report.Mappings[0].Fields {
+		if field.MatchedBy != MatchKindName {
+			t.Errorf("%s matched by %s, want %s", field.DestField, field.MatchedBy, MatchKindName)
+		}
+	}
+	// each combination of pointer and value elements is checked against the generated code
+	const behavior = `package mapping
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dkowalsky92/structmap/internal/generator/testdata/catalog"
+)
+
+func TestMapCatalogToCatalogDTO(t *testing.T) {
+	one, two := catalog.Item{ID: 1}, catalog.Item{ID: 2}
+	dst := MapCatalogToCatalogDTO(catalog.Catalog{
+		Values:   []catalog.Item{one, two},
+		Ptrs:     []*catalog.Item{&one, nil, &two},
+		ToPtrs:   []catalog.Item{one, two},
+		FromPtrs: []*catalog.Item{nil, &two},
+	})
+	if want := []catalog.ItemDTO{{ID: "1"}, {ID: "2"}}; !reflect.DeepEqual(dst.Values, want) {
+		t.Errorf("Values = %v, want %v", dst.Values, want)
+	}
+	if len(dst.Ptrs) != 3 || *dst.Ptrs[0] != (catalog.ItemDTO{ID: "1"}) || dst.Ptrs[1] != nil || *dst.Ptrs[2] != (catalog.ItemDTO{ID: "2"}) {
+		t.Errorf("Ptrs = %v, want 1, nil, 2", dst.Ptrs)
+	}
+	if len(dst.ToPtrs) != 2 || dst.ToPtrs[0] == dst.ToPtrs[1] || *dst.ToPtrs[0] != (catalog.ItemDTO{ID: "1"}) || *dst.ToPtrs[1] != (catalog.ItemDTO{ID: "2"}) {
+		t.Errorf("ToPtrs = %v, want distinct pointers to 1, 2", dst.ToPtrs)
+	}
+	if want := []catalog.ItemDTO{{}, {ID: "2"}}; !reflect.DeepEqual(dst.FromPtrs, want) {
+		t.Errorf("FromPtrs = %v, want %v", dst.FromPtrs, want)
+	}
+	if dst := MapCatalogToCatalogDTO(catalog.Catalog{}); dst.Values != nil || dst.Ptrs != nil || dst.ToPtrs != nil || dst.FromPtrs != nil {
+		t.Errorf("nil slices mapped to %+v, want nil", dst)
+	}
+}
+`
+	goCommand(t, []File{{Name: "structmap.gen.go", Code: code}, {Name: "structmap_test.go", Code: behavior}}, "test")
+}
+
+func TestGroupedFieldNames(t *testing.T) {
+	wantFields := []string{"A", "B", "C", "X", "Y", "P", "Q"}
+	checkIndependent := func(t *testing.T, fields []FieldDefinition) {
+		t.Helper()
+		var names []string
+		for _, field := range fields {
+			names = append(names, field.Name)
+		}
+		if !slices.Equal(names, wantFields) {
+			t.Fatalf("fields = %v, want %v", names, wantFields)
+		}
+		// every definition owns its imports, so changing one leaves the names declared with it alone
+		for _, pair := range [][2]int{{0, 1}, {3, 4}, {5, 6}} {
+			first, second := &fields[pair[0]], fields[pair[1]]
+			if len(first.Imports) == 0 {
+				continue
+			}
+			want := second.Imports[0]
+			first.Imports[0] = "changed"
+			if second.Imports[0] != want {
+				t.Errorf("field %s shares its imports with %s", second.Name, first.Name)
+			}
+		}
+	}
+
+	t.Run("package struct", func(t *testing.T) {
+		g := NewGenerator(Config{}, Conversions{})
+		fields, err := g.extractFieldsFromPackage(testdata+"/grids", "Cell", nil)
+		if err != nil {
+			t.Fatalf("extractFieldsFromPackage() error = %v", err)
+		}
+		for _, field := range fields[:3] {
+			if field.TypeTemplate != "int" || field.Tag != `json:"cell"` {
+				t.Errorf("field %s = %s `%s`, want int `json:\"cell\"`", field.Name, field.TypeTemplate, field.Tag)
+			}
+		}
+		checkIndependent(t, fields)
+	})
+
+	inline := NewTypeWithImportsTemplate("struct { A, B, C int; X, Y {{ .Import0 }}.Duration; P, Q *{{ .Import1 }}.Order }", []string{"time", testdata + "/orders"})
+	t.Run("inline struct", func(t *testing.T) {
+		g := NewGenerator(Config{}, Conversions{})
+		fields, err := g.extractInlineStructFields(inline)
+		if err != nil {
+			t.Fatalf("extractInlineStructFields() error = %v", err)
+		}
+		checkIndependent(t, fields)
+		if inline.Imports[0] != "time" {
+			t.Errorf("the inline struct's imports changed to %v", inline.Imports)
+		}
+	})
+
+	t.Run("mapping", func(t *testing.T) {
+		code, _ := mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Cell", imports: [$testdata/grids]}
+    to: {type: "{{ .Import0 }}.CellDTO", imports: [$testdata/grids]}
+  - from: {type: "{{ .Import0 }}.CellDTO", imports: [$testdata/grids]}
+    to: {type: "struct { A, B, C int; X, Y {{ .Import0 }}.Duration; P, Q *{{ .Import1 }}.Order }", imports: [time, $testdata/orders]}
+`)
+		for _, name := range wantFields {
+			if want := fmt.Sprintf("dst.%s = src.%s", name, name); strings.Count(code, want) != 2 {
+				t.Errorf("generated code doesn't contain %q twice:\n%s", want, code)
+			}
+		}
+		compile(t, code)
+	})
+}
+
+func TestMessyImportPaths(t *testing.T) {
+	code, _ := mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Order", imports: [" \"$testdata/orders\" "]}
+    to: {type: "{{ .Import0 }}.OrderDTO", imports: ["$testdata//orders/"]}
+    custom_field_mappings:
+      - source_field: ID
+        dest_field: ID
+        conversion:
+          tmpl: "{{ .Dest }} = {{ .Import0 }}.FormatInt({{ .Source }}, 10)"
+          imports: ["'strconv'"]
+      - source_field: Discount
+        dest_field: Discount
+        conversion:
+          tmpl: "{{ .Dest }} = {{ .Import0 }}.Sprint({{ .Source }})"
+          imports: ["fmt/"]
+`)
+	if n := strings.Count(code, "/testdata/orders\""); n != 1 {
+		t.Errorf("orders imported %d times, want once:\n%s", n, code)
+	}
+	if !strings.Contains(code, "func MapOrderToOrderDTO(src ref1.Order) (dst ref1.OrderDTO)") {
+		t.Errorf("both spellings of the orders path don't share an alias:\n%s", code)
+	}
+	compile(t, code)
+}
+
+func TestFuncFields(t *testing.T) {
+	code, report := mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Hooks", imports: [$testdata/hooks]}
+    to: {type: "{{ .Import0 }}.HooksDTO", imports: [$testdata/hooks]}
+`)
+	tests := []struct {
+		field     string
+		matchedBy MatchKind
+		want      string
+	}{
+		{field: "Validate", matchedBy: MatchKindName, want: "dst.Validate = src.Validate"},
+		{field: "Notify", matchedBy: MatchKindName, want: "dst.Notify = src.Notify"},
+		{field: "Retry", matchedBy: MatchKindSkipped, want: "// skipped unsupported field kind func for field: Retry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if !strings.Contains(code, tt.want) {
+				t.Errorf("generated code doesn't contain %q:\n%s", tt.want, code)
+			}
+			for _, field := range report.Mappings[0].Fields {
+				if field.DestField == tt.field && field.MatchedBy != tt.matchedBy {
+					t.Errorf("%s matched by %s, want %s", tt.field, field.MatchedBy, tt.matchedBy)
+				}
+			}
+		})
+	}
+
+	g := NewGenerator(Config{}, Conversions{})
+	fields, err := g.extractFieldsFromPackage(testdata+"/hooks", "Hooks", nil)
+	if err != nil {
+		t.Fatalf("extractFieldsFromPackage() error = %v", err)
+	}
+	wantImports := []string{"context", testdata + "/orders", "time"}
+	if imports := slices.Sorted(slices.Values(fields[0].Imports)); !slices.Equal(imports, wantImports) {
+		t.Errorf("Validate imports = %v, want %v", imports, wantImports)
+	}
+	compile(t, code)
+
+	code, _ = mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Batch", imports: [$testdata/hooks]}
+    to: {type: "{{ .Import0 }}.BatchDTO", imports: [$testdata/hooks]}
+`)
+	if want := "dst.Run = src.Run"; !strings.Contains(code, want) {
+		t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+	}
+	compile(t, code)
+}
+
+func TestArrayElementsAssignable(t *testing.T) {
+	tests := []struct {
+		name        string
+		conversions string
+		want        string
+		wantErr     string
+	}{
+		{
+			name:    "incompatible elements",
+			wantErr: "field Labels: Labels[i] has type int, which can't be assigned to string, add a conversion for this type pair",
+		},
+		{
+			name: "element conversion",
+			conversions: `
+conversions:
+  - source_type: int
+    dest_type: string
+    imports: [strconv]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.Itoa({{ .Source }})"
+`,
+			want: ".Itoa(src.Labels[i])",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, err := generateConfig(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Matrix", imports: [$testdata/matrices]}
+    to: {type: "{{ .Import0 }}.MatrixDTO", imports: [$testdata/matrices]}
+`+tt.conversions)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Generate() error = %v, want %q\n%s", err, tt.wantErr, code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if !strings.Contains(code, tt.want) {
+				t.Errorf("generated code doesn't contain %q:\n%s", tt.want, code)
+			}
+			compile(t, code)
+		})
+	}
+}
+
+func TestArrayAssignment(t *testing.T) {
+	tests := []struct {
+		name        string
+		to          string
+		conversions string
+		want        []string
+		wantErr     string
+	}{
+		{
+			name: "equal arrays",
+			to:   "MatrixDTO",
+			conversions: `
+conversions:
+  - source_type: int
+    dest_type: string
+    imports: [fmt]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.Sprint({{ .Source }})"
+`,
+			want: []string{"dst.Cells = src.Cells"},
+		},
+		{
+			name: "converted elements",
+			to:   "CellsDTO",
+			want: []string{
+				"for i := 0; i < 3; i++ {\n\t\tdst.Cells[i] = int64(src.Cells[i])",
+				"for i := 0; i < 2; i++ {\n\t\tdst.Readers[i] = src.Readers[i]",
+			},
+		},
+		{
+			name:    "length mismatch",
+			to:      "ShortDTO",
+			wantErr: "array length mismatch for field Short: [2] vs [3]",
+		},
+		{
+			name:    "elements without a conversion",
+			to:      "PointsDTO",
+			wantErr: "field Points: array elements of type ref1.Point can't be assigned to ref1.PointDTO, add a conversion for this type pair",
+		},
+		{
+			name: "elements with a conversion",
+			to:   "PointsDTO",
+			conversions: `
+conversions:
+  - source_type: "{{ .Import0 }}.Point"
+    dest_type: "{{ .Import0 }}.PointDTO"
+    imports: [$testdata/matrices]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.PointDTO({{ .Source }})"
+`,
+			want: []string{"dst.Points[i] = ref1.PointDTO(src.Points[i])"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, err := generateConfig(t, fmt.Sprintf(`
+mappings:
+  - from: {type: "{{ .Import0 }}.Matrix", imports: [$testdata/matrices]}
+    to: {type: "{{ .Import0 }}.%s", imports: [$testdata/matrices]}
+%s`, tt.to, tt.conversions))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Generate() error = %v, want %q\n%s", err, tt.wantErr, code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(code, want) {
+					t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+				}
+			}
+			compile(t, code)
+		})
+	}
+}
+
+func TestDependencyTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []string
+	}{
+		{
+			name: "fields of a dependency's types",
+			from: `{type: "{{ .Import0 }}.Document", imports: [$testdata/documents]}`,
+			to:   `{type: "{{ .Import0 }}.DocumentDTO", imports: [$testdata/documents]}`,
+			want: []string{"dst.Root = src.Root", "dst.Kind = src.Kind", "dst.Style = src.Style"},
+		},
+		{
+			name: "struct of a dependency",
+			from: `{type: "{{ .Import0 }}.Node", imports: [gopkg.in/yaml.v3]}`,
+			to:   `{type: "{{ .Import0 }}.NodeDTO", imports: [$testdata/documents]}`,
+			want: []string{"func MapNodeToNodeDTO(src ref1.Node) (dst ref2.NodeDTO)", "dst.Kind = src.Kind", "dst.Column = src.Column"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := mustGenerate(t, fmt.Sprintf(`
+mappings:
+  - from: %s
+    to: %s
+`, tt.from, tt.to))
+			for _, want := range tt.want {
+				if !strings.Contains(code, want) {
+					t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+				}
+			}
+			compile(t, code)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/version"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// genericsVersion is the first Go version with type parameters and the any alias.
+const genericsVersion = "go1.18"
+
+// goVersion returns the configured go_version in the go1.N form of go/version.
+func (c Config) goVersion() string {
+	if c.GoVersion == "" {
+		return ""
+	}
+	return "go" + strings.TrimPrefix(strings.TrimSpace(c.GoVersion), "go")
+}
+
+func (c Config) validateGoVersion() error {
+	if c.GoVersion != "" && !version.IsValid(c.goVersion()) {
+		return fmt.Errorf("invalid go_version %q, must be a Go version such as 1.17", c.GoVersion)
+	}
+	return nil
+}
+
+// supportsGenerics reports whether the target Go version, if any, has type parameters.
+func (c Config) supportsGenerics() bool {
+	return c.GoVersion == "" || version.Compare(c.goVersion(), genericsVersion) >= 0
+}
+
+// checkGenerics fails for generic instantiations such as Box[int] when the target Go version
+// predates generics, so the generated file doesn't break the build of an older toolchain.
+func (g *Generator) checkGenerics(funcName string, types ...TypeWithImportsTemplate) error {
+	if g.config.supportsGenerics() {
+		return nil
+	}
+	for _, t := range types {
+		expression, err := parser.ParseExpr(t.ExecuteTemplate(g.importManager))
+		if err != nil {
+			continue
+		}
+		generic := false
+		ast.Inspect(expression, func(n ast.Node) bool {
+			switch n.(type) {
+			case *ast.IndexExpr, *ast.IndexListExpr:
+				generic = true
+			}
+			return !generic
+		})
+		if generic {
+			return fmt.Errorf("mapping %s uses the generic type %s, which needs go %s, but go_version is %s", funcName, t.GetQualifiedType(g.packageName), strings.TrimPrefix(genericsVersion, "go"), g.config.GoVersion)
+		}
+	}
+	return nil
+}
+
+// targetGoVersion spells the empty interface the way the target Go version supports: any from
+// go 1.18 on, interface{} before. Without go_version the spelling of the source types is kept.
+func (g *Generator) targetGoVersion(code string) (string, error) {
+	if g.config.GoVersion == "" {
+		return code, nil
+	}
+	useAny := g.config.supportsGenerics()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", code, parser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated code: %w", err)
+	}
+	astutil.Apply(f, func(c *astutil.Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ast.InterfaceType:
+			if useAny && (n.Methods == nil || len(n.Methods.List) == 0) {
+				c.Replace(ast.NewIdent("any"))
+			}
+		case *ast.Ident:
+			if !useAny && n.Name == "any" && c.Name() != "Sel" && c.Name() != "Names" && c.Name() != "Name" {
+				c.Replace(&ast.InterfaceType{Interface: n.Pos(), Methods: &ast.FieldList{Opening: n.Pos(), Closing: n.Pos()}})
+			}
+		}
+		return true
+	}, nil)
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return "", fmt.Errorf("failed to print generated code: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ResolveIncludes appends the conversions of every file listed under include, recursively, after
+// the ones defined inline, so the including file takes precedence. Include paths are relative to
+// the directory of path, the file the conversions were read from, or to the working directory when
+// path is empty. A file included more than once is loaded once, an include cycle is an error.
+func ResolveIncludes(conversions Conversions, path string) (Conversions, error) {
+	var stack []string
+	dir := "."
+	if path != "" {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return Conversions{}, err
+		}
+		stack, dir = []string{absPath}, filepath.Dir(absPath)
+	}
+	return resolveIncludes(conversions, dir, stack, map[string]bool{})
+}
+
+func resolveIncludes(conversions Conversions, dir string, stack []string, loaded map[string]bool) (Conversions, error) {
+	resolved := Conversions{Conversions: slices.Clone(conversions.Conversions)}
+	for _, include := range conversions.Include {
+		path := include
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return Conversions{}, err
+		}
+		if slices.Contains(stack, path) {
+			return Conversions{}, fmt.Errorf("include cycle: %s", strings.Join(append(slices.Clone(stack), path), " -> "))
+		}
+		if loaded[path] {
+			continue
+		}
+		loaded[path] = true
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return Conversions{}, fmt.Errorf("failed to read include %s: %w", include, err)
+		}
+		var included Conversions
+		if err := yaml.Unmarshal(raw, &included); err != nil {
+			return Conversions{}, fmt.Errorf("failed to parse include %s: %w", include, err)
+		}
+		included, err = resolveIncludes(included, filepath.Dir(path), append(slices.Clone(stack), path), loaded)
+		if err != nil {
+			return Conversions{}, err
+		}
+		resolved.Conversions = append(resolved.Conversions, included.Conversions...)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger receives the generator's log output, *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+type LogLevel string
+
+const (
+	LogLevelNone  LogLevel = ""
+	LogLevelInfo  LogLevel = "info"
+	LogLevelDebug LogLevel = "debug"
+)
+
+func (l LogLevel) rank() int {
+	switch l {
+	case LogLevelInfo:
+		return 1
+	case LogLevelDebug:
+		return 2
+	}
+	return 0
+}
+
+// LogsAt reports whether messages of the given level are logged, debug: true implies the debug level.
+func (c Config) LogsAt(level LogLevel) bool {
+	current := c.LogLevel
+	if c.Debug {
+		current = LogLevelDebug
+	}
+	return level.rank() > 0 && current.rank() >= level.rank()
+}
+
+func (c Config) validateLogLevel() error {
+	switch c.LogLevel {
+	case LogLevelNone, LogLevelInfo, LogLevelDebug:
+		return nil
+	}
+	return fmt.Errorf("invalid log_level %q, must be one of %q or %q", c.LogLevel, LogLevelInfo, LogLevelDebug)
+}
+
+func (g *Generator) logf(level LogLevel, format string, v ...any) {
+	if !g.config.LogsAt(level) {
+		return
+	}
+	g.logger().Printf(format, v...)
+}
+
+// warnf logs regardless of the log level.
+func (g *Generator) warnf(format string, v ...any) {
+	g.logger().Printf("warning: "+format, v...)
+}
+
+func (g *Generator) logger() Logger {
+	if g.config.Logger != nil {
+		return g.config.Logger
+	}
+	return log.Default()
+}
+
+func (g *Generator) logReport(report Report) {
+	for _, mapping := range report.Mappings {
+		unmapped := mapping.Unmapped()
+		g.logf(LogLevelInfo, "%s (%s → %s): %d/%d dest fields mapped", mapping.FuncName, mapping.From, mapping.To, len(mapping.Fields)-len(unmapped), len(mapping.Fields))
+		for _, field := range mapping.Fields {
+			if field.MatchedBy == MatchKindUnmapped {
+				g.logf(LogLevelInfo, "  %s: unmapped", field.DestField)
+			} else {
+				g.logf(LogLevelInfo, "  %s: %s (%s)", field.DestField, field.Source, field.MatchedBy)
+			}
+		}
+	}
+	for _, conversion := range report.UnusedConversions {
+		if conversion.FieldName != "" || conversion.FieldTag != "" {
+			g.logf(LogLevelInfo, "unused conversion: %s → %s for %s", conversion.SourceType, conversion.DestType, scopeDescription(conversion.FieldName, conversion.FieldTag))
+		} else {
+			g.logf(LogLevelInfo, "unused conversion: %s → %s", conversion.SourceType, conversion.DestType)
+		}
+	}
+}
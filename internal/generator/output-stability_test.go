@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestAssignmentOrderGolden(t *testing.T) {
+	const config = `
+mappings:
+  - from: {type: "{{ .Import0 }}.Page", imports: [$testdata/pages]}
+    to: {type: "{{ .Import0 }}.PageDTO", imports: [$testdata/pages]}
+  - from: {type: "{{ .Import0 }}.PageDTO", imports: [$testdata/pages]}
+    to: {type: "{{ .Import0 }}.Page", imports: [$testdata/pages]}
+`
+	code, _ := mustGenerate(t, config)
+	for range 5 {
+		if again, _ := mustGenerate(t, config); again != code {
+			t.Fatalf("regenerated code differs:\n%s\nwant:\n%s", again, code)
+		}
+	}
+
+	golden := filepath.Join("testdata", "pages.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v, run the test with -update to create it", err)
+	}
+	if code != string(want) {
+		t.Errorf("generated code differs from %s:\n%s", golden, code)
+	}
+	compile(t, code)
+}
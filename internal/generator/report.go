@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+type MatchKind string
+
+const (
+	MatchKindName          MatchKind = "name"
+	MatchKindTag           MatchKind = "tag"
+	MatchKindCustom        MatchKind = "custom"
+	MatchKindAdditionalArg MatchKind = "additional_arg"
+	MatchKindComposite     MatchKind = "composite"
+	MatchKindSplit         MatchKind = "split"
+	MatchKindPosition      MatchKind = "position"
+	MatchKindMatcher       MatchKind = "matcher"
+	MatchKindSkipped       MatchKind = "skipped"
+	MatchKindUnmapped      MatchKind = "unmapped"
+)
+
+type Report struct {
+	Mappings []MappingReport
+	// UnusedConversions lists the global conversions that no generated assignment uses.
+	UnusedConversions []ConversionReport
+	// Imports maps the path of every import registered while generating to its alias, including
+	// imports that turned out unused and were dropped from the output.
+	Imports map[string]string
+}
+
+type ConversionReport struct {
+	SourceType string
+	DestType   string
+	FieldName  string
+	FieldTag   string
+}
+
+type MappingReport struct {
+	FuncName string
+	From     string
+	To       string
+	// FromType and ToType are From and To qualified by their import paths.
+	FromType string
+	ToType   string
+	Fields   []FieldReport
+}
+
+type FieldReport struct {
+	DestField string
+	Source    string
+	MatchedBy MatchKind
+	// Conversion describes the conversion applied to the source, if any, e.g. "uuid.UUID → string".
+	Conversion string
+}
+
+func (r MappingReport) Unmapped() []string {
+	var unmapped []string
+	for _, field := range r.Fields {
+		if field.MatchedBy == MatchKindUnmapped {
+			unmapped = append(unmapped, field.DestField)
+		}
+	}
+	return unmapped
+}
+
+// String renders the report as a plan listing how every dest field of every mapping is sourced,
+// followed by the unused conversions.
+func (r Report) String() string {
+	var b strings.Builder
+	for idx, mapping := range r.Mappings {
+		if idx > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(mapping.String())
+	}
+	if len(r.UnusedConversions) > 0 {
+		b.WriteString("\nunused conversions:\n")
+		for _, conversion := range r.UnusedConversions {
+			if conversion.FieldName != "" || conversion.FieldTag != "" {
+				fmt.Fprintf(&b, "  %s → %s (%s)\n", conversion.SourceType, conversion.DestType, scopeDescription(conversion.FieldName, conversion.FieldTag))
+			} else {
+				fmt.Fprintf(&b, "  %s → %s\n", conversion.SourceType, conversion.DestType)
+			}
+		}
+	}
+	return b.String()
+}
+
+func (r MappingReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s → %s\n", r.FuncName, r.FromType, r.ToType)
+	w := tabwriter.NewWriter(&b, 0, 0, 1, ' ', 0)
+	for _, field := range r.Fields {
+		switch field.MatchedBy {
+		case MatchKindUnmapped, MatchKindSkipped:
+			fmt.Fprintf(w, "  %s\t%s\n", field.DestField, field.MatchedBy)
+			continue
+		}
+		how := string(field.MatchedBy)
+		if field.Conversion != "" {
+			how += ", conversion " + field.Conversion
+		}
+		fmt.Fprintf(w, "  %s\t← %s (%s)\n", field.DestField, field.Source, how)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// describeConversion names a conversion for the report by its qualified types.
+func (g *Generator) describeConversion(conversion *Conversion, isReverse bool) string {
+	description := fmt.Sprintf("%s → %s", conversion.GetSourceTypeWithImportsTemplate().GetQualifiedType(g.packageName), conversion.GetDestTypeWithImportsTemplate().GetQualifiedType(g.packageName))
+	if isReverse {
+		return "reverse of " + description
+	}
+	return description
+}
@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUnusedConversions(t *testing.T) {
+	const conversions = `
+conversions:
+  - source_type: int64
+    dest_type: string
+    imports: [strconv]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.FormatInt({{ .Source }}, 10)"
+  - source_type: "*int"
+    dest_type: string
+    imports: [strconv]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.Itoa(*{{ .Source }})"
+  - source_type: bool
+    dest_type: string
+    imports: [strconv]
+    conversion:
+      tmpl: "{{ .Dest }} = {{ .Import0 }}.FormatBool({{ .Source }})"
+`
+	tests := []struct {
+		name       string
+		mapping    string
+		wantUnused []string
+	}{
+		{
+			name: "matched by a field",
+			mapping: `
+  - from: {type: "{{ .Import0 }}.Order", imports: [$testdata/orders]}
+    to: {type: "{{ .Import0 }}.OrderDTO", imports: [$testdata/orders]}
+`,
+			wantUnused: []string{"bool"},
+		},
+		{
+			// the *int conversion is only looked up to check the field, the field's own
+			// conversion is what's emitted
+			name: "probed, but replaced by a field conversion",
+			mapping: `
+  - from: {type: "{{ .Import0 }}.Order", imports: [$testdata/orders]}
+    to: {type: "{{ .Import0 }}.OrderDTO", imports: [$testdata/orders]}
+    custom_field_mappings:
+      - source_field: Discount
+        dest_field: Discount
+        default_on_nil: '"none"'
+        conversion:
+          tmpl: '{{ .Dest }} = {{ .Import0 }}.Sprint(*{{ .Source }})'
+          imports: [fmt]
+`,
+			wantUnused: []string{"*int", "bool"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, report := mustGenerate(t, conversions+"mappings:"+tt.mapping)
+			var unused []string
+			for _, conversion := range report.UnusedConversions {
+				unused = append(unused, conversion.SourceType)
+			}
+			if !slices.Equal(unused, tt.wantUnused) {
+				t.Errorf("UnusedConversions = %v, want %v", unused, tt.wantUnused)
+			}
+			compile(t, code)
+		})
+	}
+}
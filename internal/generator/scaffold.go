@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Scaffold renders a starter config.yaml and conversions.yaml mapping from to to, both named as
+// <import path>.<type>, e.g. "github.com/acme/app/models.User". Dest fields are paired with the
+// source field of the same name or json tag, pairs the generator can't assign on its own get a
+// placeholder conversion, and dest fields left unpaired are listed as commented-out
+// custom_field_mappings.
+func Scaffold(from string, to string) (string, string, error) {
+	fromType, err := scaffoldType(from)
+	if err != nil {
+		return "", "", err
+	}
+	toType, err := scaffoldType(to)
+	if err != nil {
+		return "", "", err
+	}
+	g := NewGenerator(Config{}, Conversions{})
+	sourceFields, err := g.extractTypeFields(fromType)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to extract fields from %s: %w", from, err)
+	}
+	destFields, err := g.extractTypeFields(toType)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to extract fields from %s: %w", to, err)
+	}
+
+	var conversions []Conversion
+	var unmatched []string
+	seen := map[string]bool{}
+	for _, dest := range destFields {
+		source, ok := scaffoldSource(sourceFields, dest)
+		if !ok {
+			unmatched = append(unmatched, dest.Name)
+			continue
+		}
+		if g.positionallyAssignable(source, dest, Mapping{}) {
+			continue
+		}
+		pair := source.key() + " → " + dest.key()
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		conversions = append(conversions, g.scaffoldConversion(source.TypeWithImportsTemplate, dest.TypeWithImportsTemplate))
+	}
+	return scaffoldConfig(fromType, toType, unmatched), scaffoldConversions(conversions), nil
+}
+
+// scaffoldType parses a type named as <import path>.<type>.
+func scaffoldType(name string) (TypeWithImportsTemplate, error) {
+	idx := strings.LastIndex(name, ".")
+	if idx <= strings.LastIndex(name, "/") || idx == len(name)-1 {
+		return TypeWithImportsTemplate{}, fmt.Errorf("invalid type %q, expected <import path>.<type>, e.g. github.com/acme/app/models.User", name)
+	}
+	return NewTypeWithImportsTemplate("{{ .Import0 }}."+name[idx+1:], []string{name[:idx]}), nil
+}
+
+func scaffoldSource(sourceFields []FieldDefinition, dest FieldDefinition) (FieldDefinition, bool) {
+	for _, source := range sourceFields {
+		if source.Name == dest.Name {
+			return source, true
+		}
+	}
+	for _, tag := range (Mapping{}).MatchTags() {
+		destTag := tagValue(dest.Tag, tag)
+		if destTag == "" {
+			continue
+		}
+		for _, source := range sourceFields {
+			if tagValue(source.Tag, tag) == destTag {
+				return source, true
+			}
+		}
+	}
+	return FieldDefinition{}, false
+}
+
+// scaffoldConversion returns a placeholder conversion between the two types, with their imports
+// merged into the conversion's imports.
+func (g *Generator) scaffoldConversion(sourceType TypeWithImportsTemplate, destType TypeWithImportsTemplate) Conversion {
+	source, dest := sourceType.GetQualifiedType(g.packageName), destType.GetQualifiedType(g.packageName)
+	var imports []string
+	renumber := func(t TypeWithImportsTemplate) string {
+		return importPlaceholderPattern.ReplaceAllStringFunc(t.TypeTemplate, func(match string) string {
+			idx, _ := strconv.Atoi(importPlaceholderPattern.FindStringSubmatch(match)[1])
+			if idx >= len(t.Imports) {
+				return match
+			}
+			merged := slices.Index(imports, t.Imports[idx])
+			if merged < 0 {
+				merged = len(imports)
+				imports = append(imports, t.Imports[idx])
+			}
+			return fmt.Sprintf("{{ .Import%d }}", merged)
+		})
+	}
+	return Conversion{
+		SourceType:        renumber(sourceType),
+		DestType:          renumber(destType),
+		Conversion:        ConversionTemplate{Tmpl: fmt.Sprintf("{{ .Dest }} = {{ .Source }} // TODO: convert %s to %s", source, dest)},
+		ReverseConversion: ConversionTemplate{Tmpl: fmt.Sprintf("{{ .Dest }} = {{ .Source }} // TODO: convert %s to %s", dest, source)},
+		Imports:           imports,
+	}
+}
+
+func scaffoldConfig(fromType TypeWithImportsTemplate, toType TypeWithImportsTemplate, unmatched []string) string {
+	var b strings.Builder
+	b.WriteString("# Generate with: structmap -config config.yaml -conversions conversions.yaml\n")
+	b.WriteString("out_package_name: mapping        # package of the generated file, change it to the package of out_file_path\n")
+	b.WriteString("out_file_name: structmap.gen.go\n")
+	b.WriteString("mappings:\n")
+	b.WriteString("  - from:\n")
+	fmt.Fprintf(&b, "      type: %s\n", strconv.Quote(fromType.TypeTemplate))
+	fmt.Fprintf(&b, "      imports: [%s]\n", strconv.Quote(fromType.Imports[0]))
+	b.WriteString("    to:\n")
+	fmt.Fprintf(&b, "      type: %s\n", strconv.Quote(toType.TypeTemplate))
+	fmt.Fprintf(&b, "      imports: [%s]\n", strconv.Quote(toType.Imports[0]))
+	if len(unmatched) > 0 {
+		b.WriteString("    # These dest fields have no source field of the same name or json tag, pick one\n")
+		b.WriteString("    # or remove the entry to leave the field unmapped.\n")
+		b.WriteString("    # custom_field_mappings:\n")
+		for _, name := range unmatched {
+			b.WriteString("    #   - source_field: \"\"\n")
+			fmt.Fprintf(&b, "    #     dest_field: %s\n", name)
+		}
+	}
+	return b.String()
+}
+
+func scaffoldConversions(conversions []Conversion) string {
+	if len(conversions) == 0 {
+		return "# Every matched field is assigned directly, add conversions for type pairs that need one.\nconversions: []\n"
+	}
+	var b strings.Builder
+	b.WriteString("# Placeholder conversions for the matched fields whose types can't be assigned directly,\n")
+	b.WriteString("# replace the TODO templates with real conversions.\n")
+	b.WriteString("conversions:\n")
+	for _, conv := range conversions {
+		fmt.Fprintf(&b, "  - source_type: %s\n", strconv.Quote(conv.SourceType))
+		fmt.Fprintf(&b, "    dest_type: %s\n", strconv.Quote(conv.DestType))
+		b.WriteString("    conversion:\n")
+		fmt.Fprintf(&b, "      tmpl: %s\n", strconv.Quote(conv.Conversion.Tmpl))
+		b.WriteString("    reverse_conversion:\n")
+		fmt.Fprintf(&b, "      tmpl: %s\n", strconv.Quote(conv.ReverseConversion.Tmpl))
+		if len(conv.Imports) > 0 {
+			quoted := make([]string, len(conv.Imports))
+			for idx, imp := range conv.Imports {
+				quoted[idx] = strconv.Quote(imp)
+			}
+			fmt.Fprintf(&b, "    imports: [%s]\n", strings.Join(quoted, ", "))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// resolveSourcePaths adds the nested source fields that custom field mappings reference by a
+// dotted path such as Address.City to byName, keyed by the path, so they're matched like any
+// other source field.
+func (g *Generator) resolveSourcePaths(mapping Mapping, byName map[string]FieldDefinition) error {
+	for _, customFieldMapping := range mapping.CustomFieldMappings {
+		for _, path := range append([]string{customFieldMapping.SourceField}, customFieldMapping.SourceFields...) {
+			if !strings.Contains(path, ".") {
+				continue
+			}
+			if _, ok := byName[path]; ok {
+				continue
+			}
+			field, err := g.resolveSourcePath(mapping, path, byName)
+			if err != nil {
+				return fmt.Errorf("custom field mapping for %s: %w", path, err)
+			}
+			byName[path] = field
+		}
+	}
+	return nil
+}
+
+// resolveSourcePath walks path through the fields of the nested structs. The returned field is
+// named by the whole path and remembers the pointers along it that need a nil check.
+func (g *Generator) resolveSourcePath(mapping Mapping, path string, byName map[string]FieldDefinition) (FieldDefinition, error) {
+	segments := strings.Split(path, ".")
+	field, ok := byName[segments[0]]
+	if !ok {
+		return FieldDefinition{}, fmt.Errorf("source field %s not found in %s", segments[0], mapping.primary().GetUnaliasedType())
+	}
+	var nilChecks []string
+	for idx, segment := range segments[1:] {
+		parent := strings.Join(segments[:idx+1], ".")
+		structType := field.TypeWithImportsTemplate
+		if strings.HasPrefix(structType.TypeTemplate, "*") {
+			structType = NewTypeWithImportsTemplate(strings.TrimPrefix(structType.TypeTemplate, "*"), structType.Imports)
+			nilChecks = append(nilChecks, parent)
+		}
+		if pkgPath, _, _, err := structType.SplitTypeArgs(); !isInlineStruct(structType) && (err != nil || pkgPath == "") {
+			return FieldDefinition{}, fmt.Errorf("%s of type %s is not a struct", parent, field.GetQualifiedType(g.packageName))
+		}
+		fields, err := g.extractTypeFields(structType)
+		if err != nil {
+			return FieldDefinition{}, fmt.Errorf("failed to load the fields of %s: %w", parent, err)
+		}
+		idx := slices.IndexFunc(fields, func(nested FieldDefinition) bool {
+			return nested.Name == segment
+		})
+		if idx < 0 {
+			return FieldDefinition{}, fmt.Errorf("source field %s not found in %s", segment, structType.GetQualifiedType(g.packageName))
+		}
+		field = fields[idx]
+	}
+	field.Name = path
+	field.nilChecks = nilChecks
+	return field, nil
+}
+
+// guardSourcePaths wraps assignment in a nil check of the pointers along the paths of nested
+// source fields, leaving the dest field untouched when one of them is nil.
+func (g *Generator) guardSourcePaths(mapping Mapping, assignment string, fields ...FieldDefinition) string {
+	var conditions []string
+	for _, field := range fields {
+		for _, nilCheck := range field.nilChecks {
+			condition := fmt.Sprintf("%s.%s != nil", g.srcVar(mapping), nilCheck)
+			if !slices.Contains(conditions, condition) {
+				conditions = append(conditions, condition)
+			}
+		}
+	}
+	if assignment == "" || len(conditions) == 0 {
+		return assignment
+	}
+	return fmt.Sprintf(`if %s {
+		%s
+	}`, strings.Join(conditions, " && "), assignment)
+}
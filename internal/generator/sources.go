@@ -0,0 +1,155 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML accepts a single struct definition for from, as well as a list of them.
+func (m *Mapping) UnmarshalYAML(node *yaml.Node) error {
+	type plain Mapping
+	if node.Kind != yaml.MappingNode {
+		return node.Decode((*plain)(m))
+	}
+	// from is decoded on its own, so the rest is decoded from a copy of the node without it
+	var from *yaml.Node
+	rest := *node
+	rest.Content = make([]*yaml.Node, 0, len(node.Content))
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == "from" {
+			from = node.Content[idx+1]
+			continue
+		}
+		rest.Content = append(rest.Content, node.Content[idx], node.Content[idx+1])
+	}
+	if err := rest.Decode((*plain)(m)); err != nil {
+		return err
+	}
+	if from == nil {
+		return nil
+	}
+	if from.Kind == yaml.MappingNode {
+		var source StructDefinition
+		if err := from.Decode(&source); err != nil {
+			return err
+		}
+		m.From = []StructDefinition{source}
+		return nil
+	}
+	return from.Decode(&m.From)
+}
+
+// primary is the first source, src, or src0 when the mapping has several.
+func (m Mapping) primary() StructDefinition {
+	if len(m.From) == 0 {
+		return StructDefinition{}
+	}
+	return m.From[0]
+}
+
+// srcVar names the parameter of the primary source, src0 when the mapping has several sources.
+func (g *Generator) srcVar(mapping Mapping) string {
+	if len(mapping.From) > 1 {
+		return g.config.SrcVar() + "0"
+	}
+	return g.config.SrcVar()
+}
+
+// withSourceArgs turns the sources following the primary one into args named src1, src2, …,
+// which follow src0 in the parameter list and whose fields are matched like those of src0.
+func (m Mapping) withSourceArgs(srcVar string) Mapping {
+	if len(m.From) < 2 {
+		return m
+	}
+	args := make([]AdditionalArg, 0, len(m.From)-1+len(m.FuncAdditionalArgs))
+	for idx, source := range m.From[1:] {
+		args = append(args, AdditionalArg{Name: fmt.Sprintf("%s%d", srcVar, idx+1), TypeWithImportsTemplate: source.TypeWithImportsTemplate, MatchFields: true, source: true})
+	}
+	m.FuncAdditionalArgs = append(args, m.FuncAdditionalArgs...)
+	return m
+}
+
+// sourceArgs returns the args of the sources following the primary one, in source_index order
+// starting at 1.
+func (m Mapping) sourceArgs() []AdditionalArg {
+	var args []AdditionalArg
+	for _, arg := range m.FuncAdditionalArgs {
+		if arg.source {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// validateSourceIndexes checks the custom field mappings that read a field of a source other
+// than the primary one, which only map one field by name.
+func validateSourceIndexes(mapping Mapping) error {
+	var errs []error
+	for _, customFieldMapping := range mapping.CustomFieldMappings {
+		if customFieldMapping.SourceIndex == 0 {
+			continue
+		}
+		field := customFieldMapping.DestField
+		if field == "" {
+			field = customFieldMapping.DestTag
+		}
+		if customFieldMapping.SourceIndex < 0 || customFieldMapping.SourceIndex >= len(mapping.From) {
+			errs = append(errs, fmt.Errorf("custom field mapping for %s: source_index %d is out of range, the mapping has %d sources", field, customFieldMapping.SourceIndex, len(mapping.From)))
+			continue
+		}
+		if customFieldMapping.SourceField == "" || customFieldMapping.DestField == "" || len(customFieldMapping.SourceFields) > 0 || len(customFieldMapping.DestFields) > 0 || customFieldMapping.OmitEmpty || customFieldMapping.DefaultOnNil != "" {
+			errs = append(errs, fmt.Errorf("custom field mapping for %s: source_index needs source_field and dest_field and can't be combined with source_fields, dest_fields, omit_empty or default_on_nil", field))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// findSourcesField matches dest against the fields of the additional sources. A custom field
+// mapping with a source_index picks the field, otherwise dest must match a field of exactly one
+// source, src included, unless src was picked by a custom field mapping. The match is returned
+// as an arg reading the field, e.g. "src1.Email", along with the custom field mapping.
+func (g *Generator) findSourcesField(mapping Mapping, dest FieldDefinition, primary *FieldDefinition, matchedBy MatchKind, tags []string) (*AdditionalArg, *CustomFieldMapping, error) {
+	sourceArgs := mapping.sourceArgs()
+	if len(sourceArgs) == 0 {
+		return nil, nil, nil
+	}
+	for _, customFieldMapping := range mapping.CustomFieldMappings {
+		if customFieldMapping.SourceIndex == 0 || customFieldMapping.DestField != dest.Name {
+			continue
+		}
+		arg := sourceArgs[customFieldMapping.SourceIndex-1]
+		fields, _ := g.GetFields(arg.matchedStruct().key())
+		idx := slices.IndexFunc(fields, func(field FieldDefinition) bool { return field.Name == customFieldMapping.SourceField })
+		if idx < 0 {
+			return nil, nil, fmt.Errorf("custom field mapping for %s: source field %s not found in %s %s", dest.Name, customFieldMapping.SourceField, arg.Name, arg.GetQualifiedType(g.packageName))
+		}
+		return &AdditionalArg{Name: arg.Name + "." + fields[idx].Name, DestField: dest.Name, TypeWithImportsTemplate: fields[idx].TypeWithImportsTemplate}, &customFieldMapping, nil
+	}
+	if matchedBy == MatchKindCustom {
+		return nil, nil, nil
+	}
+
+	var matched []string
+	if primary != nil {
+		matched = append(matched, g.srcVar(mapping)+"."+primary.Name)
+	}
+	var match *AdditionalArg
+	for _, arg := range sourceArgs {
+		fields, _ := g.GetFields(arg.matchedStruct().key())
+		if field, ok := matchArgField(fields, dest, tags); ok {
+			matched = append(matched, arg.Name+"."+field.Name)
+			match = &AdditionalArg{Name: arg.Name + "." + field.Name, DestField: dest.Name, TypeWithImportsTemplate: field.TypeWithImportsTemplate}
+		}
+	}
+	if len(matched) > 1 {
+		return nil, nil, fmt.Errorf("field %s matches %s, add a custom field mapping with source_index to pick one", dest.Name, strings.Join(matched, ", "))
+	}
+	if primary != nil {
+		return nil, nil, nil
+	}
+	return match, nil, nil
+}
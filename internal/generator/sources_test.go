@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMultipleSources(t *testing.T) {
+	const users = `
+  - from:
+      - {type: "{{ .Import0 }}.User", imports: [$testdata/people]}
+      - {type: "{{ .Import0 }}.Profile", imports: [$testdata/people]}
+    to: {type: "{{ .Import0 }}.UserDTO", imports: [$testdata/people]}
+`
+	tests := []struct {
+		name     string
+		mappings string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name: "single from",
+			mappings: `
+  - from: {type: "{{ .Import0 }}.User", imports: [$testdata/people]}
+    to: {type: "{{ .Import0 }}.UserDTO", imports: [$testdata/people]}
+`,
+			want: []string{"(src ref1.User) (dst ref1.UserDTO)", "dst.Name = src.Name"},
+		},
+		{
+			name: "single from in a list",
+			mappings: `
+  - from: [{type: "{{ .Import0 }}.User", imports: [$testdata/people]}]
+    to: {type: "{{ .Import0 }}.UserDTO", imports: [$testdata/people]}
+`,
+			want: []string{"(src ref1.User) (dst ref1.UserDTO)", "dst.Name = src.Name"},
+		},
+		{
+			name:     "ambiguous field",
+			mappings: users,
+			wantErr:  "field Name matches src0.Name, src1.Name",
+		},
+		{
+			name: "source_index picks src1",
+			mappings: users + `    custom_field_mappings:
+      - {source_field: Name, dest_field: Name, source_index: 1}
+`,
+			want: []string{
+				"(src0 ref1.User, src1 ref1.Profile) (dst ref1.UserDTO)",
+				"dst.ID = src0.ID",
+				"dst.Name = src1.Name",
+				"dst.Email = src1.Email",
+			},
+		},
+		{
+			name: "no source_index picks src0",
+			mappings: users + `    custom_field_mappings:
+      - {source_field: Name, dest_field: Name}
+`,
+			want: []string{"dst.Name = src0.Name", "dst.Email = src1.Email"},
+		},
+		{
+			name: "source_index out of range",
+			mappings: users + `    custom_field_mappings:
+      - {source_field: Name, dest_field: Name, source_index: 2}
+`,
+			wantErr: "source_index 2 is out of range, the mapping has 2 sources",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, err := generateConfig(t, "mappings:"+tt.mappings)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Generate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(code, want) {
+					t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+				}
+			}
+			compile(t, code)
+		})
+	}
+}
+
+func TestMappingUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{name: "single from", document: "from: {type: a.A}\nto: {type: b.B}\nfunc_name: MapA\n", want: []string{"a.A"}},
+		{name: "list", document: "from:\n  - {type: a.A}\n  - {type: c.C}\nto: {type: b.B}\nfunc_name: MapA\n", want: []string{"a.A", "c.C"}},
+		{name: "without from", document: "to: {type: b.B}\nfunc_name: MapA\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.document), &node); err != nil {
+				t.Fatal(err)
+			}
+			// decoding twice and re-encoding shows the node is left as it was
+			for range 2 {
+				var mapping Mapping
+				if err := node.Decode(&mapping); err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				var from []string
+				for _, source := range mapping.From {
+					from = append(from, source.TypeTemplate)
+				}
+				if !slices.Equal(from, tt.want) || mapping.To.TypeTemplate != "b.B" || mapping.FuncName != "MapA" {
+					t.Errorf("Decode() = from %v, to %s, func_name %s, want from %v, to b.B, func_name MapA", from, mapping.To.TypeTemplate, mapping.FuncName, tt.want)
+				}
+			}
+			encoded, err := yaml.Marshal(&node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.document), &want); err != nil {
+				t.Fatal(err)
+			}
+			wantEncoded, _ := yaml.Marshal(&want)
+			if string(encoded) != string(wantEncoded) {
+				t.Errorf("the node changed to:\n%s\nwant:\n%s", encoded, wantEncoded)
+			}
+		})
+	}
+
+	t.Run("invalid from", func(t *testing.T) {
+		var mapping Mapping
+		if err := yaml.Unmarshal([]byte("from: a.A\n"), &mapping); err == nil {
+			t.Error("Unmarshal() error = nil, want an error for a scalar from")
+		}
+	})
+}
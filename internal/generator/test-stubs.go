@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// testStub renders a table-driven test calling funcName with a zero-valued source and
+// zero-valued additional args, a starting point to fill in with real cases.
+func (g *Generator) testStub(mapping Mapping, funcName string, params []string, hasError bool) string {
+	testingAlias := g.importManager.AddStdImport("testing")
+
+	var argFields, callArgs, setup []string
+	zeroCase := `{name: "zero value"}`
+	for _, param := range params {
+		name, typ, _ := strings.Cut(param, " ")
+		switch {
+		case mapping.FromConcrete != nil && name == g.srcParam(mapping):
+			// a nil interface fails the type assertion, so the source holds the concrete type
+			argFields = append(argFields, fmt.Sprintf("%s %s", name, typ))
+			callArgs = append(callArgs, "tt.args."+name)
+			zeroCase = fmt.Sprintf(`{name: "zero value", args: args{%s: %s}}`, name, newConcreteValue(mapping.FromConcrete.ExecuteTemplate(g.importManager)))
+		case mapping.InPlace && name == g.config.DstVar():
+			setup = append(setup, fmt.Sprintf("dst := new(%s)", strings.TrimPrefix(typ, "*")))
+			callArgs = append(callArgs, "dst")
+		case name == contextVar && g.needsContext && !slices.Contains(mapping.AdditionalArgNames(), contextVar):
+			callArgs = append(callArgs, g.importManager.AddStdImport("context")+".Background()")
+		default:
+			argFields = append(argFields, fmt.Sprintf("%s %s", name, typ))
+			callArgs = append(callArgs, "tt.args."+name)
+		}
+	}
+
+	call := fmt.Sprintf("%s(%s)", funcName, strings.Join(callArgs, ", "))
+	var results []string
+	if !mapping.InPlace {
+		results = append(results, "dst")
+	}
+	if hasError {
+		results = append(results, "err")
+	}
+	if len(results) > 0 {
+		call = strings.Join(results, ", ") + " := " + call
+	}
+	var checks []string
+	if hasError {
+		// conversions may reject zero values, so only a panic fails the stub until real cases
+		// are added
+		checks = append(checks, fmt.Sprintf(`if err != nil {
+				t.Logf("%s() error = %%v", err)
+			}`, funcName))
+	}
+	checks = append(checks, "_ = dst")
+
+	body := append(setup, call)
+	body = append(body, checks...)
+	return fmt.Sprintf(`func %s(t *%s.T) {
+	type args struct {
+		%s
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		%s,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *%s.T) {
+			%s
+		})
+	}
+}`, testFuncName(funcName), testingAlias, strings.Join(argFields, "\n\t\t"), zeroCase, testingAlias, strings.Join(body, "\n\t\t\t"))
+}
+
+// newConcreteValue renders the zero value of the struct type typ, allocated when
+// typ is a pointer.
+func newConcreteValue(typ string) string {
+	if elem, ok := strings.CutPrefix(typ, "*"); ok {
+		return fmt.Sprintf("new(%s)", elem)
+	}
+	return typ + "{}"
+}
+
+// testFuncName prefixes funcName with Test, upper-casing its first letter so go test picks
+// up stubs of unexported mapping functions too.
+func testFuncName(funcName string) string {
+	first, size := utf8.DecodeRuneInString(funcName)
+	return "Test" + string(unicode.ToUpper(first)) + funcName[size:]
+}
+
+// testFileName names the test file accompanying the given output file.
+func testFileName(name string) string {
+	return strings.TrimSuffix(name, ".go") + "_test.go"
+}
+
+func (g *Generator) renderTestFile(packageName string, funcs []string) (string, error) {
+	code := fmt.Sprintf(`package %s
+
+%s
+
+%s
+`, packageName, g.importManager.RenderImports(), strings.Join(funcs, "\n\n"))
+	code, err := removeUnusedImports(code)
+	if err != nil {
+		return "", err
+	}
+	return g.targetGoVersion(code)
+}
@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTestStubsPass(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		wantStub string
+	}{
+		{
+			name: "from_concrete pointer",
+			config: `
+mappings:
+  - from: {type: "{{ .Import0 }}.Shape", imports: [$testdata/shapes]}
+    from_concrete: {type: "*{{ .Import0 }}.Square", imports: [$testdata/shapes]}
+    to: {type: "{{ .Import0 }}.ShapeDTO", imports: [$testdata/shapes]}
+`,
+			wantStub: `args: args{srcValue: new(`,
+		},
+		{
+			name: "from_concrete value",
+			config: `
+mappings:
+  - from: {type: "{{ .Import0 }}.Shape", imports: [$testdata/shapes]}
+    from_concrete: {type: "{{ .Import0 }}.Circle", imports: [$testdata/shapes]}
+    to: {type: "{{ .Import0 }}.ShapeDTO", imports: [$testdata/shapes]}
+`,
+			wantStub: `.Circle{}}}`,
+		},
+		{
+			name: "enum conversion rejecting the zero value",
+			config: `
+mappings:
+  - from: {type: "{{ .Import0 }}.Ticket", imports: [$testdata/tickets]}
+    to: {type: "{{ .Import0 }}.TicketDTO", imports: [$testdata/tickets]}
+conversions:
+  - source_type: string
+    dest_type: "{{ .Import0 }}.Status"
+    imports: [$testdata/tickets]
+    values:
+      - {source: '"open"', dest: "{{ .Import0 }}.StatusOpen"}
+      - {source: '"closed"', dest: "{{ .Import0 }}.StatusClosed"}
+`,
+			wantStub: `t.Logf("MapTicketToTicketDTO() error = %v", err)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(t, tt.config)
+			config.GenerateTests = true
+			files, _, err := NewGenerator(config, Conversions{}).GenerateFiles()
+			if err != nil {
+				t.Fatalf("GenerateFiles() error = %v", err)
+			}
+			if len(files) != 2 || !files[1].Stub {
+				t.Fatalf("GenerateFiles() = %d files, want the output and its test stub", len(files))
+			}
+			if !strings.Contains(files[1].Code, tt.wantStub) {
+				t.Errorf("test stub doesn't contain %q:\n%s", tt.wantStub, files[1].Code)
+			}
+			goCommand(t, files, "test")
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package accounts
+
+import "time"
+
+type Audit struct {
+	CreatedAt time.Time
+	Note      string
+}
+
+type Meta struct {
+	Audit
+	Note    string
+	Version int
+}
+
+type Flags struct {
+	Version int
+	Active  bool
+}
+
+type Account struct {
+	Meta
+	Flags
+	ID   string
+	Note string
+}
+
+// Shared embeds two structs declaring Version at the same depth, which Go can't select.
+type Shared struct {
+	Meta
+	Flags
+}
+
+type AccountDTO struct {
+	ID        string
+	Note      string
+	CreatedAt time.Time
+	Version   int
+	Active    bool
+}
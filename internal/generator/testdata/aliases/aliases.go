@@ -0,0 +1,13 @@
+package aliases
+
+import (
+	p "github.com/dkowalsky92/structmap/internal/generator/testdata/profiles"
+)
+
+type Profile = p.Profile
+
+type Member p.Profile
+
+type Latest = Current
+
+type Current Member
@@ -0,0 +1,13 @@
+package boxes
+
+type Box[T any] struct {
+	Label string
+	Value T
+	Items []T
+}
+
+type BoxDTO[T any] struct {
+	Label string
+	Value T
+	Items []T
+}
@@ -0,0 +1,23 @@
+package catalog
+
+type Item struct {
+	ID int64
+}
+
+type ItemDTO struct {
+	ID string
+}
+
+type Catalog struct {
+	Values   []Item
+	Ptrs     []*Item
+	ToPtrs   []Item
+	FromPtrs []*Item
+}
+
+type CatalogDTO struct {
+	Values   []ItemDTO
+	Ptrs     []*ItemDTO
+	ToPtrs   []*ItemDTO
+	FromPtrs []ItemDTO
+}
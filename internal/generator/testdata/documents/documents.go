@@ -0,0 +1,25 @@
+package documents
+
+import "gopkg.in/yaml.v3"
+
+type Document struct {
+	Name  string
+	Root  yaml.Node
+	Kind  yaml.Kind
+	Style *yaml.Style
+}
+
+type DocumentDTO struct {
+	Name  string
+	Root  yaml.Node
+	Kind  yaml.Kind
+	Style *yaml.Style
+}
+
+type NodeDTO struct {
+	Kind   yaml.Kind
+	Tag    string
+	Value  string
+	Line   int
+	Column int
+}
@@ -0,0 +1,40 @@
+package events
+
+import "time"
+
+// Point is comparable, so its zero check is a plain comparison.
+type Point struct {
+	X, Y int
+}
+
+// Window holds a slice, which makes it incomparable.
+type Window struct {
+	Start time.Time
+	Days  []time.Weekday
+}
+
+type Event struct {
+	Name     string
+	Location Point
+	Window   Window
+	Grid     [2][2]int
+	Slots    [2][]string
+	Handlers struct{ OnStart func() }
+}
+
+type EventPatch struct {
+	Name     string
+	Location Point
+	Window   Window
+	Grid     [2][2]int
+	Slots    [2][]string
+	Handlers struct{ OnStart func() }
+}
+
+type EventDTO struct {
+	Name     *string
+	Location *Point
+	Window   *Window
+	Grid     *[2][2]int
+	Slots    *[2][]string
+}
@@ -0,0 +1,22 @@
+package files
+
+import "io"
+
+type File struct {
+	Name string
+	Body io.Reader
+	Err  error
+	Meta any
+}
+
+type FileDTO struct {
+	Name string
+	Body io.Reader
+	Err  error
+	Meta interface{}
+}
+
+type UploadDTO struct {
+	Name string
+	Body io.ReadCloser
+}
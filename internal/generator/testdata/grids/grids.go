@@ -0,0 +1,19 @@
+package grids
+
+import (
+	"time"
+
+	"github.com/dkowalsky92/structmap/internal/generator/testdata/orders"
+)
+
+type Cell struct {
+	A, B, C int `json:"cell"`
+	X, Y    time.Duration
+	P, Q    *orders.Order
+}
+
+type CellDTO struct {
+	C, B, A int
+	Y, X    time.Duration
+	Q, P    *orders.Order
+}
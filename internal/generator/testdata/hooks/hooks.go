@@ -0,0 +1,28 @@
+package hooks
+
+import (
+	"context"
+	"time"
+
+	o "github.com/dkowalsky92/structmap/internal/generator/testdata/orders"
+)
+
+type Hooks struct {
+	Validate func(ctx context.Context, order o.Order) (time.Duration, error)
+	Notify   func(*o.Order) error
+	Retry    func(attempt int) time.Duration
+}
+
+type HooksDTO struct {
+	Validate func(context.Context, o.Order) (time.Duration, error)
+	Notify   func(*o.Order) error
+	Retry    func(attempt int64) time.Duration
+}
+
+type Batch struct {
+	Run func(first, last o.Order, rest ...o.Order) (n int, err error)
+}
+
+type BatchDTO struct {
+	Run func(o.Order, o.Order, ...o.Order) (int, error)
+}
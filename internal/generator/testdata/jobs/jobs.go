@@ -0,0 +1,17 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/dkowalsky92/structmap/internal/generator/testdata/queues"
+)
+
+type Batch struct {
+	Pending queues.List[time.Duration]
+	Index   queues.Pair[string, *time.Location]
+}
+
+type BatchDTO struct {
+	Pending queues.List[time.Duration]
+	Index   queues.Pair[string, *time.Location]
+}
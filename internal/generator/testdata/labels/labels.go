@@ -0,0 +1,24 @@
+package labels
+
+import "fmt"
+
+type Named interface {
+	Name() string
+}
+
+type Tag struct {
+	fmt.Stringer
+	Named
+	Key string
+}
+
+type TagDTO struct {
+	Key string
+	fmt.Stringer
+	Named
+}
+
+type TagView struct {
+	Stringer fmt.Stringer
+	Key      string
+}
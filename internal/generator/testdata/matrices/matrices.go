@@ -0,0 +1,40 @@
+package matrices
+
+import (
+	"io"
+	"strings"
+)
+
+type Point struct {
+	X, Y int
+}
+
+type PointDTO struct {
+	X, Y int
+}
+
+type Matrix struct {
+	Cells   [3]int
+	Labels  [2]int
+	Short   [2]int
+	Points  [2]Point
+	Readers [2]*strings.Reader
+}
+
+type MatrixDTO struct {
+	Cells  [3]int
+	Labels [2]string
+}
+
+type CellsDTO struct {
+	Cells   [3]int64
+	Readers [2]io.Reader
+}
+
+type ShortDTO struct {
+	Short [3]int
+}
+
+type PointsDTO struct {
+	Points [2]PointDTO
+}
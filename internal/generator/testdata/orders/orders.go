@@ -0,0 +1,13 @@
+package orders
+
+type Order struct {
+	ID       int64
+	Quantity int
+	Discount *int
+}
+
+type OrderDTO struct {
+	ID       string
+	Quantity int64
+	Discount string
+}
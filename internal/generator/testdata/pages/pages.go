@@ -0,0 +1,30 @@
+package pages
+
+import "time"
+
+type Base struct {
+	ID      int
+	Created time.Time
+}
+
+type Extra struct {
+	Lang  string
+	Draft bool
+}
+
+type Page struct {
+	Draft bool
+	Extra
+	Title string
+	Base
+	Slug string
+	Lang string
+}
+
+type PageDTO struct {
+	Slug string
+	Base
+	Title string
+	Extra
+	Views int
+}
@@ -0,0 +1,18 @@
+package people
+
+type User struct {
+	ID   int
+	Name string
+}
+
+type Profile struct {
+	Name  string
+	Email string
+	Bio   string
+}
+
+type UserDTO struct {
+	ID    int
+	Name  string
+	Email string
+}
@@ -0,0 +1,17 @@
+package profiles
+
+type Score int64
+
+type Profile struct {
+	Nick   string
+	Tags   []string
+	Scores []int
+	Rank   Score
+}
+
+type ProfileDTO struct {
+	Nick   string
+	Tags   []string
+	Scores []int
+	Rank   Score
+}
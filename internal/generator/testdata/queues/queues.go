@@ -0,0 +1,10 @@
+package queues
+
+type List[T any] struct {
+	Items []T
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
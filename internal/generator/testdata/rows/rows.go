@@ -0,0 +1,15 @@
+package rows
+
+type Row struct {
+	First  string `json:"first" db:"col_a"`
+	Second string `json:",omitempty" db:"col_b"`
+	Third  string `db:"col_c"`
+	Fourth string `json:"fourth" db:"col_d"`
+}
+
+type RowDTO struct {
+	A string `json:"a" db:"a"`
+	B string `json:"b" db:"b"`
+	C string `db:"c"`
+	D string `json:"d" db:"d"`
+}
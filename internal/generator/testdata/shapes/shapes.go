@@ -0,0 +1,26 @@
+package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side float64
+}
+
+func (s *Square) Area() float64 { return s.Side * s.Side }
+
+type Circle struct {
+	Radius float64
+}
+
+func (c Circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type Label struct {
+	Side float64
+}
+
+type ShapeDTO struct {
+	Side   float64
+	Radius float64
+}
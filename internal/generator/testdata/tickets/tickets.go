@@ -0,0 +1,18 @@
+package tickets
+
+type Status int
+
+const (
+	StatusOpen Status = iota + 1
+	StatusClosed
+)
+
+type Ticket struct {
+	Title  string
+	Status string
+}
+
+type TicketDTO struct {
+	Title  string
+	Status Status
+}
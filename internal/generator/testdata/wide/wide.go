@@ -0,0 +1,111 @@
+// Package wide holds structs with many fields of identical types, which are mostly copied as
+// they are.
+package wide
+
+import "time"
+
+type Record struct {
+	Field00 string
+	Field01 int
+	Field02 int64
+	Field03 bool
+	Field04 float64
+	Field05 time.Time
+	Field06 []string
+	Field07 map[string]int
+	Field08 *string
+	Field09 uint8
+	Field10 string
+	Field11 int
+	Field12 int64
+	Field13 bool
+	Field14 float64
+	Field15 time.Time
+	Field16 []string
+	Field17 map[string]int
+	Field18 *string
+	Field19 uint8
+	Field20 string
+	Field21 int
+	Field22 int64
+	Field23 bool
+	Field24 float64
+	Field25 time.Time
+	Field26 []string
+	Field27 map[string]int
+	Field28 *string
+	Field29 uint8
+	Field30 string
+	Field31 int
+	Field32 int64
+	Field33 bool
+	Field34 float64
+	Field35 time.Time
+	Field36 []string
+	Field37 map[string]int
+	Field38 *string
+	Field39 uint8
+	Field40 string
+	Field41 int
+	Field42 int64
+	Field43 bool
+	Field44 float64
+	Field45 time.Time
+	Field46 []string
+	Field47 map[string]int
+	Field48 *string
+	Field49 uint8
+}
+
+type RecordDTO struct {
+	Field00 string
+	Field01 int
+	Field02 int64
+	Field03 bool
+	Field04 float64
+	Field05 time.Time
+	Field06 []string
+	Field07 map[string]int
+	Field08 *string
+	Field09 uint8
+	Field10 string
+	Field11 int
+	Field12 int64
+	Field13 bool
+	Field14 float64
+	Field15 time.Time
+	Field16 []string
+	Field17 map[string]int
+	Field18 *string
+	Field19 uint8
+	Field20 string
+	Field21 int
+	Field22 int64
+	Field23 bool
+	Field24 float64
+	Field25 time.Time
+	Field26 []string
+	Field27 map[string]int
+	Field28 *string
+	Field29 uint8
+	Field30 string
+	Field31 int
+	Field32 int64
+	Field33 bool
+	Field34 float64
+	Field35 time.Time
+	Field36 []string
+	Field37 map[string]int
+	Field38 *string
+	Field39 uint8
+	Field40 string
+	Field41 int
+	Field42 int64
+	Field43 bool
+	Field44 float64
+	Field45 time.Time
+	Field46 []string
+	Field47 map[string]int
+	Field48 *string
+	Field49 uint8
+}
@@ -0,0 +1,138 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// hasTypePattern reports whether the conversion matches a family of types by regex, see
+// findPatternConversion.
+func (c Conversion) hasTypePattern() bool {
+	return c.SourceTypePattern != "" || c.DestTypePattern != ""
+}
+
+func (c Conversion) validateTypePatterns() error {
+	if !c.hasTypePattern() {
+		return nil
+	}
+	var errs []error
+	for _, pattern := range []string{c.SourceTypePattern, c.DestTypePattern} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid type pattern %q: %w", pattern, err))
+		}
+	}
+	if (c.SourceTypePattern != "" && c.SourceType != "") || (c.DestTypePattern != "" && c.DestType != "") {
+		errs = append(errs, fmt.Errorf("source_type_pattern and dest_type_pattern replace source_type and dest_type, only one of each pair can be set"))
+	}
+	if c.Conversion.UseFunc != "" || c.ReverseConversion.UseFunc != "" || c.MatchUnderlying || len(c.Values) > 0 {
+		errs = append(errs, fmt.Errorf("type patterns can't be combined with use_func, match_underlying or values"))
+	}
+	return errors.Join(errs...)
+}
+
+// typePattern compiles pattern to match whole type names.
+func (g *Generator) typePattern(pattern string) *regexp.Regexp {
+	if re, ok := g.typePatterns[pattern]; ok {
+		return re
+	}
+	re := regexp.MustCompile("^(?:" + pattern + ")$")
+	g.typePatterns[pattern] = re
+	return re
+}
+
+// matchTypeSide matches t against pattern, or against exact when there's no pattern, and
+// records the capture groups as <prefix>Match<N> and <prefix>Match_<name>.
+func (g *Generator) matchTypeSide(pattern string, exact TypeWithImportsTemplate, t TypeWithImportsTemplate, prefix string, captures map[string]string) bool {
+	if pattern == "" {
+		return exact.Equals(t)
+	}
+	re := g.typePattern(pattern)
+	match := re.FindStringSubmatch(t.GetQualifiedType(g.packageName))
+	if match == nil {
+		return false
+	}
+	for idx, name := range re.SubexpNames() {
+		if idx == 0 {
+			continue
+		}
+		captures[prefix+"Match"+strconv.Itoa(idx)] = match[idx]
+		if name != "" {
+			captures[prefix+"Match_"+name] = match[idx]
+		}
+	}
+	return true
+}
+
+// findPatternConversion returns the first conversion, custom_conversions first, whose type
+// patterns match the field types, turned into a conversion between these types, see
+// patternConversion. It's consulted once conversions of the exact types have failed.
+func (g *Generator) findPatternConversion(
+	sourceType TypeWithImportsTemplate,
+	destType TypeWithImportsTemplate,
+	fieldName string,
+	mapping Mapping,
+	sameType bool,
+	trace *conversionTrace,
+) (*Conversion, bool) {
+	for idx, conv := range append(slices.Clone(mapping.CustomConversions), g.conversions.Conversions...) {
+		if !conv.hasTypePattern() || (conv.scoped() && !conv.inScope(fieldName, g.fieldTags)) {
+			continue
+		}
+		if sameType && !conv.ApplyToSameType {
+			continue
+		}
+		list, listIdx := "custom_conversions", idx
+		if idx >= len(mapping.CustomConversions) {
+			list, listIdx = "conversions", idx-len(mapping.CustomConversions)
+			if g.conversionDisabled(conv, mapping) {
+				continue
+			}
+		}
+		for _, isReverse := range []bool{false, true} {
+			if isReverse && !conv.ReverseConversion.defined() {
+				continue
+			}
+			// the conversion's source side is the dest field in reverse
+			convSource, convDest := sourceType, destType
+			if isReverse {
+				convSource, convDest = destType, sourceType
+			}
+			captures := map[string]string{}
+			if !g.matchTypeSide(conv.SourceTypePattern, conv.GetSourceTypeWithImportsTemplate(), convSource, "Source", captures) ||
+				!g.matchTypeSide(conv.DestTypePattern, conv.GetDestTypeWithImportsTemplate(), convDest, "Dest", captures) {
+				continue
+			}
+			if trace != nil {
+				label := fmt.Sprintf("%s[%d] %s → %s", list, listIdx, conv.SourceType+conv.SourceTypePattern, conv.DestType+conv.DestTypePattern)
+				forward, reverse := "", "pattern mismatch"
+				if isReverse {
+					forward, reverse = reverse, forward
+				}
+				trace.compare(label, forward, reverse)
+			}
+			return patternConversion(conv, convSource, convDest, isReverse, captures), isReverse
+		}
+	}
+	return nil, false
+}
+
+// patternConversion turns conv into a conversion between the matched types for the direction
+// it's used in. The template's imports are merged into the conversion's, so {{ .ImportN }}
+// keeps its meaning, followed by the imports of the matched types.
+func patternConversion(conv Conversion, convSource TypeWithImportsTemplate, convDest TypeWithImportsTemplate, isReverse bool, captures map[string]string) *Conversion {
+	tmpl := &conv.Conversion
+	if isReverse {
+		tmpl = &conv.ReverseConversion
+	}
+	imports := append(slices.Clone(conv.Imports), tmpl.Imports...)
+	tmpl.Imports = nil
+	conv.SourceType = shiftImports(convSource, len(imports))
+	conv.DestType = shiftImports(convDest, len(imports)+len(convSource.Imports))
+	conv.Imports = append(append(imports, convSource.Imports...), convDest.Imports...)
+	conv.SourceTypePattern, conv.DestTypePattern = "", ""
+	conv.captures = captures
+	return &conv
+}
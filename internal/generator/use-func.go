@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"slices"
+	"strings"
+)
+
+// funcSignature describes the results and parameters of a generated mapper that use_func
+// callers need to know about.
+type funcSignature struct {
+	returnsError bool
+	needsContext bool
+}
+
+// defined reports whether the template converts values, through tmpl or use_func.
+func (t ConversionTemplate) defined() bool {
+	return t.Tmpl != "" || t.UseFunc != ""
+}
+
+func (t ConversionTemplate) validateUseFunc() error {
+	if t.UseFunc == "" {
+		return nil
+	}
+	if !token.IsIdentifier(t.UseFunc) {
+		return fmt.Errorf("use_func %q is not a valid Go identifier", t.UseFunc)
+	}
+	if t.Tmpl != "" {
+		return fmt.Errorf("use_func %s replaces tmpl, only one of them can be set", t.UseFunc)
+	}
+	if t.Error || t.OnNotOk != "" || t.Default != "" || len(t.Imports) > 0 {
+		return fmt.Errorf("use_func %s can't be combined with error, on_not_ok, default or imports, the called mapper decides whether an error is returned", t.UseFunc)
+	}
+	return nil
+}
+
+// mappingKey identifies a mapping in circular mapping errors.
+func (g *Generator) mappingKey(mapping Mapping) string {
+	return fmt.Sprintf("%s → %s", mapping.primary().GetQualifiedType(g.packageName), mapping.To.GetQualifiedType(g.packageName))
+}
+
+// mappingFuncNames returns the index of the mapping generating every function name.
+func (g *Generator) mappingFuncNames() (map[string]int, error) {
+	funcs := map[string]int{}
+	for idx, mapping := range g.config.Mappings {
+		funcName := mapping.FuncName
+		if funcName == "" {
+			var err error
+			funcName, err = g.funcName(mapping.primary().TypeWithImportsTemplate, mapping.To.TypeWithImportsTemplate)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := funcs[funcName]; !ok {
+			funcs[funcName] = idx
+		}
+	}
+	return funcs, nil
+}
+
+// validateUseFuncs checks that every use_func names a mapping that converts the conversion's
+// types in that direction and can be called with the value alone.
+func (g *Generator) validateUseFuncs() error {
+	checkConversion := func(conversion Conversion, callerPackage string) error {
+		for _, isReverse := range []bool{false, true} {
+			tmpl, direction := conversion.Conversion, "conversion"
+			sourceType, destType := conversion.GetSourceTypeWithImportsTemplate(), conversion.GetDestTypeWithImportsTemplate()
+			if isReverse {
+				tmpl, direction = conversion.ReverseConversion, "reverse_conversion"
+				sourceType, destType = destType, sourceType
+			}
+			if err := g.checkUseFunc(tmpl.UseFunc, &sourceType, &destType, callerPackage); err != nil {
+				return fmt.Errorf("conversion %s → %s: %s %w", conversion.SourceType, conversion.DestType, direction, err)
+			}
+		}
+		return nil
+	}
+	var errs []error
+	// shared conversions may be used by mappings of any package
+	for _, conversion := range g.conversions.Conversions {
+		if err := checkConversion(conversion, ""); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, mapping := range g.config.Mappings {
+		for _, conversion := range mapping.CustomConversions {
+			if err := checkConversion(conversion, g.mappingPackage(mapping)); err != nil {
+				errs = append(errs, fmt.Errorf("mapping %s: %w", g.mappingKey(mapping), err))
+			}
+		}
+		// the types of field conversions are those of the fields, which are only known once loaded
+		for _, customFieldMapping := range mapping.CustomFieldMappings {
+			if customFieldMapping.Conversion == nil {
+				continue
+			}
+			if err := g.checkUseFunc(customFieldMapping.Conversion.UseFunc, nil, nil, g.mappingPackage(mapping)); err != nil {
+				errs = append(errs, fmt.Errorf("mapping %s: custom field mapping for %s: conversion %w", g.mappingKey(mapping), customFieldMapping.DestField+customFieldMapping.DestTag, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkUseFunc checks that useFunc, when set, is generated by a mapping taking src alone,
+// from sourceType to destType when they're given, into callerPackage unless it's empty.
+func (g *Generator) checkUseFunc(useFunc string, sourceType *TypeWithImportsTemplate, destType *TypeWithImportsTemplate, callerPackage string) error {
+	if useFunc == "" {
+		return nil
+	}
+	idx, ok := g.mappingFuncs[useFunc]
+	if !ok {
+		return fmt.Errorf("use_func %s is not generated by any mapping", useFunc)
+	}
+	mapping := g.config.Mappings[idx]
+	if sourceType != nil && destType != nil && (!mapping.primary().Equals(*sourceType) || !mapping.To.Equals(*destType)) {
+		return fmt.Errorf("use_func %s maps %s, not %s → %s", useFunc, g.mappingKey(mapping), sourceType.GetQualifiedType(g.packageName), destType.GetQualifiedType(g.packageName))
+	}
+	if mapping.InPlace || len(mapping.FuncAdditionalArgs) > 0 || mapping.FromConcrete != nil {
+		return fmt.Errorf("use_func %s can only call mappers taking src alone, without in_place, func_additional_args or from_concrete", useFunc)
+	}
+	if packageName := g.mappingPackage(mapping); callerPackage != "" && packageName != callerPackage {
+		return fmt.Errorf("use_func %s is generated into package %s, but called from package %s", useFunc, packageName, callerPackage)
+	}
+	return nil
+}
+
+func (g *Generator) mappingPackage(mapping Mapping) string {
+	if mapping.OutPackageName != "" {
+		return mapping.OutPackageName
+	}
+	return g.config.OutPackageName
+}
+
+// useFuncConversion returns conversion with the template of the isReverse direction calling
+// the mapper named by its use_func, generating that mapper first when it comes later in the
+// config. The call passes ctx when the mapper takes one and assigns err when it returns one.
+func (g *Generator) useFuncConversion(conversion *Conversion, isReverse bool) *Conversion {
+	converted := *conversion
+	tmpl := &converted.Conversion
+	if isReverse {
+		tmpl = &converted.ReverseConversion
+	}
+	if tmpl.UseFunc == "" {
+		return conversion
+	}
+	signature := g.funcSignature(tmpl.UseFunc)
+	args := "{{ .Source }}"
+	if signature.needsContext {
+		args = "{{ .Ctx }}, " + args
+		converted.NeedsContext = true
+	}
+	tmpl.Tmpl = fmt.Sprintf("{{ .Dest }} = %s(%s)", tmpl.UseFunc, args)
+	if signature.returnsError {
+		tmpl.Tmpl = fmt.Sprintf("{{ .Dest }}, {{ .Error }} = %s(%s)", tmpl.UseFunc, args)
+		tmpl.Error = true
+	}
+	return &converted
+}
+
+// funcSignature returns the signature of the named mapper, which is generated on demand.
+// A mapper that is being generated, because it calls itself through use_func, directly or
+// through other mappers, has no signature yet, which is recorded in useFuncErr.
+func (g *Generator) funcSignature(funcName string) funcSignature {
+	if signature, ok := g.funcSignatures[funcName]; ok {
+		return signature
+	}
+	idx := g.mappingFuncs[funcName]
+	if key := g.mappingKey(g.config.Mappings[idx]); slices.Contains(g.mappingPath, key) {
+		if g.useFuncErr == nil {
+			g.useFuncErr = fmt.Errorf("use_func %s is called while it's generated: %s, mappers can't call themselves through use_func", funcName, strings.Join(append(slices.Clone(g.mappingPath), key), " -> "))
+		}
+		return funcSignature{}
+	}
+	if _, done := g.mappingResults[idx]; !done {
+		g.generateMapping(idx)
+	}
+	return g.funcSignatures[funcName]
+}
@@ -0,0 +1,32 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pointerTo reports whether dest is a pointer to the source type, the fields zero_to_nil applies to.
+func pointerTo(sourceType TypeWithImportsTemplate, destType TypeWithImportsTemplate) bool {
+	pointee, ok := strings.CutPrefix(strings.TrimSpace(destType.TypeTemplate), "*")
+	return ok && NewTypeWithImportsTemplate(pointee, destType.Imports).Equals(sourceType)
+}
+
+// zeroToNilAssignment assigns a T source to a *T dest only when it's non-zero, leaving the dest
+// nil otherwise. Without a conversion matching the pair the dest points at the source.
+func (g *Generator) zeroToNilAssignment(
+	mapping Mapping,
+	sourceExpr string,
+	sourceType TypeWithImportsTemplate,
+	destExpr string,
+	dest FieldDefinition,
+	conversion *Conversion,
+	isReverse bool,
+) (string, bool) {
+	assignment, hasError := fmt.Sprintf("%s = &%s", destExpr, sourceExpr), false
+	if conversion != nil {
+		assignment, hasError = g.assignmentWithConversion(mapping, sourceExpr, sourceType, destExpr, dest, conversion, isReverse)
+	}
+	return fmt.Sprintf(`if %s {
+		%s
+	}`, g.nonZeroCondition(sourceExpr, sourceType), assignment), hasError
+}
@@ -0,0 +1,34 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestZeroToNil(t *testing.T) {
+	code, _ := mustGenerate(t, `
+mappings:
+  - from: {type: "{{ .Import0 }}.Event", imports: [$testdata/events]}
+    to: {type: "{{ .Import0 }}.EventDTO", imports: [$testdata/events]}
+    zero_to_nil: true
+`)
+	tests := []struct {
+		field     string
+		condition string
+	}{
+		{field: "Name", condition: `src.Name != ""`},
+		{field: "Location", condition: "src.Location != (ref1.Point{})"},
+		{field: "Window", condition: "!reflect.ValueOf(src.Window).IsZero()"},
+		{field: "Grid", condition: "src.Grid != ([2][2]int{})"},
+		{field: "Slots", condition: "!reflect.ValueOf(src.Slots).IsZero()"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			want := "if " + tt.condition + " {\n\t\tdst." + tt.field + " = &src." + tt.field + "\n\t}"
+			if !strings.Contains(code, want) {
+				t.Errorf("generated code doesn't contain %q:\n%s", want, code)
+			}
+		})
+	}
+	compile(t, code)
+}
@@ -2,53 +2,94 @@ package imports
 
 import (
 	"fmt"
+	"maps"
+	"path"
+	"sort"
 	"strings"
 )
 
 type ImportManager struct {
 	imports      map[string]string
+	aliasPrefix  string
 	aliasCounter int
 }
 
 func NewImportManager() *ImportManager {
 	return &ImportManager{
 		imports:      make(map[string]string),
+		aliasPrefix:  "ref",
 		aliasCounter: 1,
 	}
 }
 
-func (im *ImportManager) AddImport(importPath string) {
-	if !strings.Contains(importPath, "/") {
-		return
+// SetAliasPrefix replaces the ref prefix of the aliases given to imports added afterwards.
+func (im *ImportManager) SetAliasPrefix(prefix string) {
+	im.aliasPrefix = prefix
+}
+
+// CleanPath normalizes an import path as written in a config, so every spelling of a path
+// gets the same alias: surrounding whitespace and quotes are removed, and so are trailing
+// and repeated slashes, e.g. ` "github.com/x//y/" ` becomes github.com/x/y.
+func CleanPath(importPath string) string {
+	importPath = strings.TrimSpace(strings.Trim(strings.TrimSpace(importPath), "\"'`"))
+	for strings.Contains(importPath, "//") {
+		importPath = strings.ReplaceAll(importPath, "//", "/")
 	}
-	importPath = strings.TrimSpace(importPath)
+	return strings.TrimSuffix(importPath, "/")
+}
+
+func (im *ImportManager) AddImport(importPath string) {
+	importPath = CleanPath(importPath)
 	if importPath == "" {
 		return
 	}
-	importPath = strings.Trim(importPath, "\"")
 	if _, exists := im.imports[importPath]; exists {
 		return
 	}
 
-	alias := fmt.Sprintf("ref%d", im.aliasCounter)
+	alias := fmt.Sprintf("%s%d", im.aliasPrefix, im.aliasCounter)
 	im.aliasCounter++
 
 	im.imports[importPath] = alias
 }
 
+func (im *ImportManager) AddStdImport(importPath string) string {
+	importPath = CleanPath(importPath)
+	if alias, exists := im.imports[importPath]; exists {
+		return alias
+	}
+
+	alias := path.Base(importPath)
+	im.imports[importPath] = alias
+	return alias
+}
+
 func (im *ImportManager) GetImportAlias(importPath string) string {
-	importPath = strings.Trim(importPath, "\"")
-	return im.imports[importPath]
+	return im.imports[CleanPath(importPath)]
 }
 
-func (im *ImportManager) RenderImports(pattern string) string {
+// Imports returns a copy of the registered imports, keyed by import path with their alias as value.
+func (im *ImportManager) Imports() map[string]string {
+	return maps.Clone(im.imports)
+}
+
+func (im *ImportManager) RenderImports() string {
 	if len(im.imports) == 0 {
 		return ""
 	}
 
+	importPaths := make([]string, 0, len(im.imports))
+	for importPath := range im.imports {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+
 	var imports []string
-	for importPath, alias := range im.imports {
-		if strings.Contains(pattern, alias+".") {
+	for _, importPath := range importPaths {
+		alias := im.imports[importPath]
+		if alias == path.Base(importPath) {
+			imports = append(imports, fmt.Sprintf("\t\"%s\"", importPath))
+		} else {
 			imports = append(imports, fmt.Sprintf("\t%s \"%s\"", alias, importPath))
 		}
 	}
@@ -0,0 +1,83 @@
+package imports
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestAliasPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   []string
+	}{
+		{name: "default", want: []string{"ref1", "ref2", "ref1"}},
+		{name: "custom", prefix: "sm", want: []string{"sm1", "sm2", "sm1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im := NewImportManager()
+			if tt.prefix != "" {
+				im.SetAliasPrefix(tt.prefix)
+			}
+			paths := []string{"example.com/a", "example.com/b", "example.com/a"}
+			for idx, importPath := range paths {
+				im.AddImport(importPath)
+				if alias := im.GetImportAlias(importPath); alias != tt.want[idx] {
+					t.Errorf("GetImportAlias(%s) = %s, want %s", importPath, alias, tt.want[idx])
+				}
+			}
+			if alias := im.AddStdImport("encoding/json"); alias != "json" {
+				t.Errorf("AddStdImport() = %s, want json", alias)
+			}
+		})
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		importPath string
+		want       string
+	}{
+		{importPath: "github.com/x/y", want: "github.com/x/y"},
+		{importPath: "  github.com/x/y\t", want: "github.com/x/y"},
+		{importPath: `"github.com/x/y"`, want: "github.com/x/y"},
+		{importPath: ` " github.com/x/y " `, want: "github.com/x/y"},
+		{importPath: "'github.com/x/y'", want: "github.com/x/y"},
+		{importPath: "`github.com/x/y`", want: "github.com/x/y"},
+		{importPath: "github.com/x/y/", want: "github.com/x/y"},
+		{importPath: "github.com//x///y//", want: "github.com/x/y"},
+		{importPath: `"time"`, want: "time"},
+		{importPath: `""`, want: ""},
+		{importPath: "   ", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.importPath, func(t *testing.T) {
+			if got := CleanPath(tt.importPath); got != tt.want {
+				t.Errorf("CleanPath(%q) = %q, want %q", tt.importPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddImportSpellings(t *testing.T) {
+	im := NewImportManager()
+	for _, importPath := range []string{"github.com/x/y", ` "github.com/x/y" `, "github.com//x/y/", "", `""`} {
+		im.AddImport(importPath)
+	}
+	im.AddImport("github.com/x/z")
+	if alias := im.AddStdImport(` "strings" `); alias != "strings" {
+		t.Errorf("AddStdImport() = %s, want strings", alias)
+	}
+	want := map[string]string{"github.com/x/y": "ref1", "github.com/x/z": "ref2", "strings": "strings"}
+	if got := im.Imports(); !maps.Equal(got, want) {
+		t.Errorf("Imports() = %v, want %v", got, want)
+	}
+	if alias := im.GetImportAlias("'github.com/x//y'"); alias != "ref1" {
+		t.Errorf("GetImportAlias() = %q, want ref1", alias)
+	}
+	wantRendered := "import (\n\tref1 \"github.com/x/y\"\n\tref2 \"github.com/x/z\"\n\t\"strings\"\n)"
+	if got := im.RenderImports(); got != wantRendered {
+		t.Errorf("RenderImports() = %s, want %s", got, wantRendered)
+	}
+}
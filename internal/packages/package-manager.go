@@ -1,30 +1,158 @@
 package packages
 
 import (
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/dkowalsky92/structmap/internal/imports"
 	"golang.org/x/tools/go/packages"
 )
 
+var ErrPackageNotFound = errors.New("package not found")
+
+// PackageErrors is returned along with the package when it loaded, but reported errors, such as
+// a syntax error in one of its files.
+type PackageErrors struct {
+	PkgPath string
+	Errors  []packages.Error
+}
+
+func (e *PackageErrors) Error() string {
+	return fmt.Sprintf("package %s has errors: %v", e.PkgPath, e.Errors)
+}
+
+// Loader loads the package with the given import path, it needs to fill in at
+// least Name, PkgPath and either Syntax or GoFiles.
+type Loader func(pkgPath string) (*packages.Package, error)
+
 type PackageManager struct {
-	packageCache map[string]*packages.Package
+	mu           sync.Mutex
+	loader       Loader
+	packageCache map[string]*cachedPackage
+	// warn receives the errors of packages that are used despite them, see IgnoreErrors.
+	warn func(err error)
+	// sources maps import paths to the file or directory they're parsed from, see AddSource.
+	sources map[string]string
+	// customLoader is set by SetLoader, whose packages aren't type checked by LookupType.
+	customLoader bool
+	typesCache   map[string]*cachedPackage
+}
+
+type cachedPackage struct {
+	once sync.Once
+	pkg  *packages.Package
+	err  error
 }
 
 func NewPackageManager() *PackageManager {
 	return &PackageManager{
-		packageCache: make(map[string]*packages.Package),
+		loader:       loadPackage,
+		packageCache: make(map[string]*cachedPackage),
+		sources:      make(map[string]string),
+		typesCache:   make(map[string]*cachedPackage),
 	}
 }
 
+// AddSource makes GetPackage parse the package with the given import path from source, a .go
+// file or a directory of them, instead of loading it, for packages that can't be loaded, such
+// as generated code in an odd layout. Relative sources are resolved against the working
+// directory.
+func (pm *PackageManager) AddSource(pkgPath string, source string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.sources[imports.CleanPath(pkgPath)] = source
+	pm.packageCache = make(map[string]*cachedPackage)
+}
+
+// SetLoader replaces the loader used for packages that aren't cached yet, e.g. to
+// serve synthetic packages in tests. The cache is reset.
+func (pm *PackageManager) SetLoader(loader Loader) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.loader = loader
+	pm.customLoader = true
+	pm.packageCache = make(map[string]*cachedPackage)
+}
+
+// IgnoreErrors makes GetPackage use packages that loaded with errors, as long as their files
+// could be listed, passing the errors to warn instead of failing. Fields are extracted from the
+// syntax trees, so an error elsewhere in the package often doesn't affect them.
+func (pm *PackageManager) IgnoreErrors(warn func(err error)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.warn = warn
+}
+
+// GetPackage loads the package once per path, failed loads are cached as well
+// so later lookups return the same error without hitting the loader again. A loader
+// returning neither a package nor an error counts as ErrPackageNotFound.
 func (pm *PackageManager) GetPackage(pkgPath string) (*packages.Package, error) {
-	if pkg, exists := pm.packageCache[pkgPath]; exists {
-		return pkg, nil
+	pkgPath = imports.CleanPath(pkgPath)
+	pm.mu.Lock()
+	entry, exists := pm.packageCache[pkgPath]
+	if !exists {
+		entry = &cachedPackage{}
+		pm.packageCache[pkgPath] = entry
+	}
+	loader, warn := pm.loader, pm.warn
+	if source, ok := pm.sources[pkgPath]; ok {
+		loader = func(pkgPath string) (*packages.Package, error) {
+			return parsePackage(pkgPath, source)
+		}
 	}
+	pm.mu.Unlock()
 
-	pkg, err := loadPackage(pkgPath)
+	entry.once.Do(func() {
+		entry.pkg, entry.err = loader(pkgPath)
+		if entry.pkg == nil && entry.err == nil {
+			entry.err = fmt.Errorf("%w: %s", ErrPackageNotFound, pkgPath)
+		}
+		var pkgErrors *PackageErrors
+		if warn != nil && entry.pkg != nil && errors.As(entry.err, &pkgErrors) {
+			warn(entry.err)
+			entry.err = nil
+		}
+	})
+	return entry.pkg, entry.err
+}
 
-	pm.packageCache[pkgPath] = pkg
-	return pkg, err
+// LookupType returns the type checked object of the named type, or nil when it can't be type
+// checked, e.g. because its package is parsed from source, served by a custom loader or doesn't
+// compile. Loading types is slow, so it's meant for checks the syntax trees can't answer.
+func (pm *PackageManager) LookupType(pkgPath string, name string) types.Object {
+	pkgPath = imports.CleanPath(pkgPath)
+	pm.mu.Lock()
+	if _, ok := pm.sources[pkgPath]; ok || pm.customLoader {
+		pm.mu.Unlock()
+		return nil
+	}
+	entry, exists := pm.typesCache[pkgPath]
+	if !exists {
+		entry = &cachedPackage{}
+		pm.typesCache[pkgPath] = entry
+	}
+	pm.mu.Unlock()
+
+	entry.once.Do(func() {
+		// dependencies are type checked from source as well, export data may be newer than
+		// the type checker supports
+		pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedImports | packages.NeedDeps}, pkgPath)
+		if err == nil && len(pkgs) == 1 && len(pkgs[0].Errors) == 0 {
+			entry.pkg = pkgs[0]
+		}
+	})
+	if entry.pkg == nil || entry.pkg.Types == nil {
+		return nil
+	}
+	return entry.pkg.Types.Scope().Lookup(name)
 }
 
 func loadPackage(pkgPath string) (*packages.Package, error) {
@@ -36,12 +164,80 @@ func loadPackage(pkgPath string) (*packages.Package, error) {
 		return nil, fmt.Errorf("failed to load package %s: %w", pkgPath, err)
 	}
 	if len(pkgs) == 0 {
-		return nil, fmt.Errorf("package not found: %s", pkgPath)
+		return nil, fmt.Errorf("%w: %s", ErrPackageNotFound, pkgPath)
 	}
 	pkg := pkgs[0]
 	if len(pkg.Errors) > 0 {
-		return nil, fmt.Errorf("package errors: %v", pkg.Errors)
+		if len(pkg.GoFiles) == 0 {
+			return nil, fmt.Errorf("%w: %s: %v", ErrPackageNotFound, pkgPath, pkg.Errors)
+		}
+		return pkg, &PackageErrors{PkgPath: pkgPath, Errors: pkg.Errors}
+	}
+
+	return pkg, nil
+}
+
+// parsePackage parses the package with the given import path from source, a .go file or a
+// directory whose .go files, test files aside, make up the package.
+func parsePackage(pkgPath string, source string) (*packages.Package, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrPackageNotFound, pkgPath, err)
+	}
+	goFiles := []string{source}
+	if info.IsDir() {
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source of package %s: %w", pkgPath, err)
+		}
+		goFiles = nil
+		for _, entry := range entries {
+			if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+				goFiles = append(goFiles, filepath.Join(source, name))
+			}
+		}
+		if len(goFiles) == 0 {
+			return nil, fmt.Errorf("%w: %s: no .go files in %s", ErrPackageNotFound, pkgPath, source)
+		}
 	}
 
+	pkg := &packages.Package{ID: pkgPath, PkgPath: pkgPath, GoFiles: goFiles, Fset: token.NewFileSet()}
+	var pkgErrors []packages.Error
+	for _, goFile := range goFiles {
+		f, err := parser.ParseFile(pkg.Fset, goFile, nil, parser.ParseComments)
+		if err != nil {
+			pkgErrors = append(pkgErrors, packages.Error{Pos: goFile, Msg: err.Error(), Kind: packages.ParseError})
+		}
+		if f == nil {
+			continue
+		}
+		if pkg.Name == "" {
+			pkg.Name = f.Name.Name
+		} else if f.Name.Name != pkg.Name {
+			return nil, fmt.Errorf("source of package %s mixes packages %s and %s", pkgPath, pkg.Name, f.Name.Name)
+		}
+		pkg.Syntax = append(pkg.Syntax, f)
+	}
+	if len(pkgErrors) > 0 {
+		return pkg, &PackageErrors{PkgPath: pkgPath, Errors: pkgErrors}
+	}
 	return pkg, nil
 }
+
+// Files returns the parsed files of pkg, parsing its GoFiles when the loader
+// didn't provide the syntax trees.
+func Files(pkg *packages.Package) []*ast.File {
+	if len(pkg.Syntax) > 0 {
+		return pkg.Syntax
+	}
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(pkg.GoFiles))
+	for _, gofile := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, gofile, nil, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
@@ -0,0 +1,80 @@
+package packages
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestGetPackageCachesFailedLoads(t *testing.T) {
+	loadErr := errors.New("boom")
+	tests := []struct {
+		name    string
+		loader  Loader
+		wantErr error
+	}{
+		{
+			name:    "loader error",
+			loader:  func(string) (*packages.Package, error) { return nil, loadErr },
+			wantErr: loadErr,
+		},
+		{
+			name:    "nil package without error",
+			loader:  func(string) (*packages.Package, error) { return nil, nil },
+			wantErr: ErrPackageNotFound,
+		},
+		{
+			name: "package not found",
+			loader: func(pkgPath string) (*packages.Package, error) {
+				return nil, &PackageErrors{PkgPath: pkgPath}
+			},
+			wantErr: &PackageErrors{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			pm := NewPackageManager()
+			pm.SetLoader(func(pkgPath string) (*packages.Package, error) {
+				calls++
+				return tt.loader(pkgPath)
+			})
+
+			pkg1, err1 := pm.GetPackage("example.com/missing")
+			pkg2, err2 := pm.GetPackage(" \"example.com//missing/\" ")
+			if pkg1 != nil || pkg2 != nil {
+				t.Fatalf("GetPackage() returned a package for a failed load")
+			}
+			if err1 == nil || err1 != err2 {
+				t.Fatalf("GetPackage() errors = %v, %v, want the same cached error", err1, err2)
+			}
+			var pkgErrors *PackageErrors
+			if target, ok := tt.wantErr.(*PackageErrors); ok {
+				if !errors.As(err1, &pkgErrors) {
+					t.Fatalf("GetPackage() error = %v, want %T", err1, target)
+				}
+			} else if !errors.Is(err1, tt.wantErr) {
+				t.Fatalf("GetPackage() error = %v, want %v", err1, tt.wantErr)
+			}
+			if calls != 1 {
+				t.Fatalf("loader called %d times, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestGetPackageNonexistent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("loads packages with the go command")
+	}
+	pm := NewPackageManager()
+	_, err1 := pm.GetPackage("github.com/dkowalsky92/structmap/internal/nonexistent")
+	_, err2 := pm.GetPackage("github.com/dkowalsky92/structmap/internal/nonexistent")
+	if err1 == nil {
+		t.Fatal("GetPackage() error = nil, want an error for a nonexistent package")
+	}
+	if err1 != err2 {
+		t.Fatalf("GetPackage() errors = %v, %v, want the same cached error", err1, err2)
+	}
+}
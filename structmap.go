@@ -0,0 +1,112 @@
+// Package structmap generates strongly-typed mapper functions between Go structs.
+package structmap
+
+import (
+	"fmt"
+	"go/format"
+
+	"github.com/dkowalsky92/structmap/internal/generator"
+	"github.com/dkowalsky92/structmap/internal/packages"
+)
+
+type (
+	Config                  = generator.Config
+	Mapping                 = generator.Mapping
+	StructDefinition        = generator.StructDefinition
+	AdditionalArg           = generator.AdditionalArg
+	CustomFieldMapping      = generator.CustomFieldMapping
+	Conversions             = generator.Conversions
+	Conversion              = generator.Conversion
+	ConversionTemplate      = generator.ConversionTemplate
+	EnumValue               = generator.EnumValue
+	TypeWithImportsTemplate = generator.TypeWithImportsTemplate
+	Report                  = generator.Report
+	MappingReport           = generator.MappingReport
+	FieldReport             = generator.FieldReport
+	ConversionReport        = generator.ConversionReport
+	MatchKind               = generator.MatchKind
+	FieldDefinition         = generator.FieldDefinition
+	FieldKind               = generator.FieldKind
+	FieldMatcher            = generator.FieldMatcher
+	FieldMatcherFunc        = generator.FieldMatcherFunc
+	File                    = generator.File
+	Logger                  = generator.Logger
+	LogLevel                = generator.LogLevel
+	ErrorType               = generator.ErrorType
+	NotOkAction             = generator.NotOkAction
+)
+
+const (
+	LogLevelNone  = generator.LogLevelNone
+	LogLevelInfo  = generator.LogLevelInfo
+	LogLevelDebug = generator.LogLevelDebug
+)
+
+const (
+	NotOkSkip    = generator.NotOkSkip
+	NotOkDefault = generator.NotOkDefault
+	NotOkError   = generator.NotOkError
+)
+
+const (
+	MatchKindName          = generator.MatchKindName
+	MatchKindTag           = generator.MatchKindTag
+	MatchKindCustom        = generator.MatchKindCustom
+	MatchKindAdditionalArg = generator.MatchKindAdditionalArg
+	MatchKindComposite     = generator.MatchKindComposite
+	MatchKindSplit         = generator.MatchKindSplit
+	MatchKindPosition      = generator.MatchKindPosition
+	MatchKindMatcher       = generator.MatchKindMatcher
+	MatchKindSkipped       = generator.MatchKindSkipped
+	MatchKindUnmapped      = generator.MatchKindUnmapped
+)
+
+var (
+	ErrPackageNotFound = packages.ErrPackageNotFound
+	ErrTypeNotFound    = generator.ErrTypeNotFound
+)
+
+// Generate renders the mapper functions described by config and returns the gofmt-ed source of the output file.
+func Generate(config Config, conversions Conversions) (string, error) {
+	code, _, err := GenerateWithReport(config, conversions)
+	return code, err
+}
+
+// GenerateWithReport is like Generate, but also reports how every dest field of every mapping was sourced.
+func GenerateWithReport(config Config, conversions Conversions) (string, Report, error) {
+	code, report, err := generator.NewGenerator(config, conversions).GenerateWithReport()
+	if err != nil {
+		return "", Report{}, err
+	}
+	formattedCode, err := format.Source([]byte(code))
+	if err != nil {
+		return "", Report{}, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return string(formattedCode), report, nil
+}
+
+// ResolveIncludes loads the conversions files listed under include, relative to path, the file conversions were read from.
+func ResolveIncludes(conversions Conversions, path string) (Conversions, error) {
+	return generator.ResolveIncludes(conversions, path)
+}
+
+// GenerateFiles renders the output files described by config, one per mapping when config.SplitFiles is set, with names relative to config.OutDir().
+func GenerateFiles(config Config, conversions Conversions) ([]File, Report, error) {
+	files, report, err := generator.NewGenerator(config, conversions).GenerateFiles()
+	if err != nil {
+		return nil, Report{}, err
+	}
+	for idx, file := range files {
+		formattedCode, err := format.Source([]byte(file.Code))
+		if err != nil {
+			return nil, Report{}, fmt.Errorf("failed to format generated file %s: %w", file.Name, err)
+		}
+		files[idx].Code = string(formattedCode)
+	}
+	return files, report, nil
+}
+
+// Scaffold renders a starter config.yaml and conversions.yaml mapping from to to, both named as <import path>.<type>.
+func Scaffold(from string, to string) (config string, conversions string, err error) {
+	return generator.Scaffold(from, to)
+}
@@ -0,0 +1,45 @@
+package structmap_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dkowalsky92/structmap"
+)
+
+func TestGenerateNotFoundErrors(t *testing.T) {
+	const orders = "github.com/dkowalsky92/structmap/internal/generator/testdata/orders"
+	tests := []struct {
+		name    string
+		from    structmap.StructDefinition
+		wantErr error
+	}{
+		{
+			name:    "missing type",
+			from:    structmap.StructDefinition{TypeWithImportsTemplate: structmap.TypeWithImportsTemplate{TypeTemplate: "{{ .Import0 }}.Missing", Imports: []string{orders}}},
+			wantErr: structmap.ErrTypeNotFound,
+		},
+		{
+			name:    "missing package",
+			from:    structmap.StructDefinition{TypeWithImportsTemplate: structmap.TypeWithImportsTemplate{TypeTemplate: "{{ .Import0 }}.Order", Imports: []string{"github.com/dkowalsky92/structmap/internal/missing"}}},
+			wantErr: structmap.ErrPackageNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if testing.Short() {
+				t.Skip("loads packages with the go command")
+			}
+			_, err := structmap.Generate(structmap.Config{
+				OutPackageName: "mapping",
+				Mappings: []structmap.Mapping{{
+					From: []structmap.StructDefinition{tt.from},
+					To:   structmap.StructDefinition{TypeWithImportsTemplate: structmap.TypeWithImportsTemplate{TypeTemplate: "{{ .Import0 }}.OrderDTO", Imports: []string{orders}}},
+				}},
+			}, structmap.Conversions{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}